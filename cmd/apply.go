@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyRoot     string
+	applyDryRun   bool
+	applyExcludes []string
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <response>",
+	Short: "Apply the diffs or SEARCH/REPLACE blocks of a model response",
+	Long: `Apply the diffs or SEARCH/REPLACE blocks of a model response.
+
+Finds unified diffs and SEARCH/REPLACE blocks in the response, wherever they
+are in the text, and applies them to the files below --root. Hunks that do
+not match at their line number are looked for nearby, then ignoring
+whitespace, then with fewer context lines. Files with a hunk that cannot be
+applied are left untouched and reported.
+
+Paths outside the root, files a scan would skip (.gitignore and the default
+exclude patterns such as .git and node_modules, plus those given with
+--exclude) and credential files such as .env are refused. Use - to read the
+response from stdin.`,
+	Example: `  code2txt apply reply.md --root ./my-project --dry-run   # Preview the changes
+  code2txt apply reply.md --root ./my-project             # Then apply them`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var response []byte
+		var err error
+		if args[0] == "-" {
+			response, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			response, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		patches, err := internal.ParsePatches(string(response))
+		if err != nil {
+			return err
+		}
+
+		check := internal.ProtectedPaths(applyRoot, applyExcludes)
+		results := internal.ApplyPatches(applyRoot, patches, check)
+		out := cmd.OutOrStdout()
+		if applyDryRun {
+			for _, result := range results {
+				if result.Err == nil {
+					fmt.Fprint(out, internal.UnifiedDiff(patchName("a/", result.Path, result.Created),
+						patchName("b/", result.Path, result.Deleted), result.Before, result.After))
+				}
+			}
+		}
+
+		// Failures are reported even with --quiet
+		failed := 0
+		for _, result := range results {
+			if result.Failed() {
+				failed++
+				reportPatch(cmd.ErrOrStderr(), result)
+			} else {
+				reportPatch(statusWriter(cmd), result)
+			}
+		}
+
+		if !applyDryRun {
+			written, err := internal.WritePatchResults(applyRoot, results)
+			if err != nil {
+				return fmt.Errorf("failed to write patched files: %w", err)
+			}
+			fmt.Fprintf(statusWriter(cmd), "Patched %d files in %s\n", written, applyRoot)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d files could not be patched", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// patchName is the name of a file in a diff header, /dev/null when it does
+// not exist on that side
+func patchName(prefix, path string, missing bool) string {
+	if missing {
+		return "/dev/null"
+	}
+	return prefix + path
+}
+
+// reportPatch describes how the hunks of a file applied
+func reportPatch(w io.Writer, result *internal.PatchResult) {
+	if result.Err != nil {
+		fmt.Fprintf(w, "FAILED %s: %v\n", result.Path, result.Err)
+		return
+	}
+
+	status := "ok"
+	switch {
+	case result.Failed():
+		status = "FAILED"
+	case result.Created:
+		status = "new"
+	case result.Deleted:
+		status = "deleted"
+	}
+	fmt.Fprintf(w, "%s %s (%d hunks)\n", status, result.Path, len(result.Hunks))
+
+	for i, hunk := range result.Hunks {
+		switch {
+		case hunk.Err != nil:
+			fmt.Fprintf(w, "  hunk %d failed: %v\n", i+1, hunk.Err)
+		case hunk.Fuzz != "":
+			fmt.Fprintf(w, "  hunk %d applied at line %d, %s\n", i+1, hunk.Line, hunk.Fuzz)
+		}
+	}
+}
+
+func init() {
+	applyCmd.Flags().StringVar(&applyRoot, "root", ".",
+		"Folder the paths in the response are relative to")
+	applyCmd.Flags().BoolVarP(&applyDryRun, "dry-run", "n", false,
+		"Print the resulting diff and the hunk report without writing anything")
+	applyCmd.Flags().StringSliceVarP(&applyExcludes, "exclude", "e", []string{},
+		"Also refuse files matching these patterns (comma-separated)\n"+
+			"The default excludes, such as .git and node_modules, always apply")
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+const banner = `
+                                                                	  
+   ██████╗ ██████╗ ██████╗ ███████╗██████╗ ████████╗██╗  ██╗████████╗  
+  ██╔════╝██╔═══██╗██╔══██╗██╔════╝╚════██╗╚══██╔══╝╚██╗██╔╝╚══██╔══╝  
+  ██║     ██║   ██║██║  ██║█████╗   █████╔╝   ██║    ╚███╔╝    ██║     
+  ██║     ██║   ██║██║  ██║██╔══╝  ██╔═══╝    ██║    ██╔██╗    ██║     
+  ╚██████╗╚██████╔╝██████╔╝███████╗███████╗   ██║   ██╔╝ ██╗   ██║     
+   ╚═════╝ ╚═════╝ ╚═════╝ ╚══════╝╚══════╝   ╚═╝   ╚═╝  ╚═╝   ╚═╝     
+                                                                      `
+
+// statusWriter returns where the banner, progress and status messages go.
+// Only the payload is written to stdout so the output can be piped safely.
+func statusWriter(cmd *cobra.Command) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return cmd.ErrOrStderr()
+}
+
+func printBanner(cmd *cobra.Command) {
+	w := statusWriter(cmd)
+	fmt.Fprintln(w, banner)
+	fmt.Fprintf(w, "version %s\n", buildInfo.Version)
+	fmt.Fprintln(w)
+}
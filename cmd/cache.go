@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+// openCache returns the scan cache, or nil when caching is disabled or the
+// cache directory cannot be determined
+func openCache(cmd *cobra.Command) *internal.Cache {
+	if noCache {
+		return nil
+	}
+
+	cache, err := defaultCache()
+	if err != nil {
+		fmt.Fprintf(statusWriter(cmd), "Warning: cache disabled: %v\n", err)
+		return nil
+	}
+	return cache
+}
+
+func defaultCache() (*internal.Cache, error) {
+	dir, err := internal.DefaultCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return internal.NewCache(dir), nil
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the scan cache",
+	Long: `Inspect or clear the scan cache.
+
+code2txt caches the content and token count of every file it reads, keyed by
+path, size and modification time, so repeated scans of the same tree skip
+unchanged files. The cache lives in the user cache directory, or in
+$CODE2TXT_CACHE_DIR when set. Use --no-cache to bypass it for a single run.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := defaultCache()
+		if err != nil {
+			return err
+		}
+		if err := cache.Clear(); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+		fmt.Fprintf(statusWriter(cmd), "Cache cleared: %s\n", cache.Dir())
+		return nil
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number and size of cached entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := defaultCache()
+		if err != nil {
+			return err
+		}
+		stats, err := cache.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to read cache: %w", err)
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Cache directory: %s\n", stats.Dir)
+		fmt.Fprintf(out, "Entries: %d\n", stats.Entries)
+		fmt.Fprintf(out, "Size: %d bytes\n", stats.Bytes)
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
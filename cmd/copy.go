@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/nav9v/code2txt/internal"
+)
+
+// copyToClipboard copies output using the selected clipboard backend and
+// returns the name of the backend that was used
+func copyToClipboard(output string) (string, error) {
+	// OSC 52 sequences must reach the terminal even when stdout and
+	// stderr are redirected, so prefer the controlling tty
+	var terminal io.Writer = os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		terminal = tty
+	}
+
+	clipboard, err := internal.NewClipboard(clipboardName, &internal.ClipboardEnv{
+		Getenv:   os.Getenv,
+		Terminal: terminal,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := clipboard.Copy(output); err != nil {
+		return "", err
+	}
+	return clipboard.Name(), nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffMaxTokens int
+	diffBudget    int
+	diffFormat    string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare the tokens of two scans",
+	Long: `Compare the tokens of two scans.
+
+Each side is a JSON dump (code2txt -f json), a folder or archive, or a git
+ref written as <repo>@<ref>. The report lists added, removed and modified
+files with their token deltas, and the deltas per directory and language.
+With --max-tokens or --budget it also flags files, or the total, that went
+over or back under the limit.`,
+	Example: `  code2txt diff last-week.json .              # Growth since a saved dump
+  code2txt diff .@v1.0 .@main --budget 100000  # Between two refs`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diffFormat != "text" && diffFormat != "json" {
+			return fmt.Errorf("unknown report format %q (available: text, json)", diffFormat)
+		}
+
+		results := make([]*internal.ScanResult, 2)
+		for i, arg := range args {
+			result, err := loadScan(cmd.Context(), cmd, arg)
+			if err != nil {
+				return err
+			}
+			results[i] = result
+		}
+
+		diff := internal.CompareScans(results[0], results[1], internal.DiffThresholds{
+			MaxTokens: diffMaxTokens,
+			Budget:    diffBudget,
+		})
+
+		if diffFormat == "json" {
+			data, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+		fmt.Fprint(cmd.OutOrStdout(), internal.FormatScanDiff(diff))
+		return nil
+	},
+}
+
+// loadScan reads a JSON dump, or scans a folder, archive or repo@ref
+func loadScan(ctx context.Context, cmd *cobra.Command, arg string) (*internal.ScanResult, error) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() && strings.HasSuffix(arg, ".json") {
+		return internal.ReadJSONScan(arg)
+	}
+
+	path, ref := arg, ""
+	if _, err := os.Stat(arg); err != nil {
+		if at := strings.LastIndex(arg, "@"); at > 0 {
+			path, ref = arg[:at], arg[at+1:]
+		}
+	}
+
+	source, err := internal.OpenSource(ctx, path, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	scanner := internal.NewScanner(&internal.ScanOptions{
+		IncludePatterns: includePatterns,
+		ExcludePatterns: excludePatterns,
+		Cache:           openCache(cmd),
+	})
+	result, err := scanner.ScanSource(ctx, source)
+	if err != nil {
+		return nil, scanError(err)
+	}
+	return result, nil
+}
+
+func init() {
+	diffCmd.Flags().StringSliceVarP(&includePatterns, "include", "i", []string{},
+		"Only compare files matching these patterns when scanning (comma-separated)")
+	diffCmd.Flags().StringSliceVarP(&excludePatterns, "exclude", "e", []string{},
+		"Exclude files/folders matching these patterns when scanning (comma-separated)")
+	diffCmd.Flags().IntVar(&diffMaxTokens, "max-tokens", 0,
+		"Flag files that went over or back under N tokens")
+	diffCmd.Flags().IntVar(&diffBudget, "budget", 0,
+		"Flag a total that went over or back under N tokens")
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text",
+		"Report format: text, json")
+	addCacheFlag(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+// Flags shared by every command that scans a folder
+var (
+	includePatterns []string
+	excludePatterns []string
+	maxTokens       int
+	noCache         bool
+	redactSecrets   bool
+	noTests         bool
+	noGenerated     bool
+	onlyTests       bool
+	skipClasses     []string
+	languageNames   []string
+)
+
+// Flags shared by every command that renders the full output
+var (
+	showTokens   bool
+	noTree       bool
+	outputFormat string
+	lineNumbers  bool
+	templateFile string
+	promptName   string
+	promptHeader string
+	promptFooter string
+	// Shared with the tree command
+	treeDepth         int
+	collapseThreshold int
+)
+
+// addScanFlags registers the flags that control which files are scanned
+func addScanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&includePatterns, "include", "i", []string{},
+		"Only include files matching these patterns (comma-separated)\n"+
+			"Example: -i \"*.go,*.js,*.py\" (only Go, JavaScript, Python files)")
+
+	cmd.Flags().StringSliceVarP(&excludePatterns, "exclude", "e", []string{},
+		"Exclude files/folders matching these patterns (comma-separated)\n"+
+			"Example: -e \"*.log,node_modules,target,dist\" (skip logs & build dirs)")
+
+	cmd.Flags().IntVar(&maxTokens, "max-tokens", 0,
+		"Skip files larger than N tokens (0 = no limit)\n"+
+			"Example: --max-tokens 5000 (skip files over 5k tokens)")
+
+	addCacheFlag(cmd)
+
+	cmd.Flags().BoolVar(&redactSecrets, "redact", false,
+		"Skip credential files (.env, private keys) and mask secrets such as\n"+
+			"API keys, tokens and passwords in the output (bypasses the scan cache)")
+
+	cmd.Flags().BoolVar(&noTests, "no-tests", false,
+		"Skip test files (_test.go, *.spec.ts, tests/ ...)")
+
+	cmd.Flags().BoolVar(&noGenerated, "no-generated", false,
+		"Skip generated code (DO NOT EDIT headers, *.pb.go, zz_generated*)")
+
+	cmd.Flags().BoolVar(&onlyTests, "only-tests", false,
+		"Only include test files")
+	cmd.MarkFlagsMutuallyExclusive("no-tests", "only-tests")
+
+	cmd.Flags().StringSliceVar(&skipClasses, "skip-class", []string{},
+		"Skip files of these classes (comma-separated)\n"+
+			"One of: "+strings.Join(internal.FileClasses(), ", "))
+
+	cmd.Flags().StringSliceVar(&languageNames, "lang", []string{},
+		"Only include files in these languages (comma-separated)\n"+
+			"Example: --lang go,proto (see 'code2txt languages')")
+}
+
+// addCacheFlag registers --no-cache, for commands that scan without the
+// other scan flags
+func addCacheFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&noCache, "no-cache", false,
+		"Read every file instead of reusing cached content and token counts\n"+
+			"See 'code2txt cache --help'")
+}
+
+// addOutputFlags registers the flags that control the rendered output
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&showTokens, "tokens", false,
+		"Display estimated token count for each file and total\n"+
+			"Useful for estimating AI model costs (GPT-4, Claude, etc.)")
+
+	cmd.Flags().BoolVar(&noTree, "no-tree", false,
+		"Skip the directory tree visualization in output\n"+
+			"Only show file contents (faster for large projects)")
+
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text",
+		"Output format: "+strings.Join(internal.OutputFormats(), ", ")+"\n"+
+			"Example: -f markdown")
+
+	cmd.Flags().IntVar(&treeDepth, "tree-depth", 0,
+		"Only show N levels of the directory tree (0 = no limit)\n"+
+			"The file contents are not affected")
+
+	cmd.Flags().IntVar(&collapseThreshold, "collapse-threshold", 0,
+		"Fold the small files of directories holding more than N files into one\n"+
+			"tree line with their totals, e.g. \"… (37 files, 12.4k tokens)\"")
+
+	cmd.Flags().BoolVarP(&lineNumbers, "line-numbers", "n", false,
+		"Prefix every line of file content with its line number\n"+
+			"Token counts and --budget include the numbers")
+
+	cmd.Flags().StringVar(&templateFile, "template", "",
+		"Render the output through this Go text/template file instead of --format\n"+
+			"Start from a built-in one: code2txt template export markdown")
+
+	cmd.Flags().StringVarP(&promptName, "prompt", "p", "",
+		"Wrap the output in a prompt preset (see 'code2txt prompts')\n"+
+			"Example: --prompt review")
+
+	cmd.Flags().StringVar(&promptHeader, "prompt-header", "",
+		"Text, or a file with the text, written before the output\n"+
+			"Overrides the header of --prompt")
+
+	cmd.Flags().StringVar(&promptFooter, "prompt-footer", "",
+		"Text, or a file with the text, written after the output\n"+
+			"Example: --prompt-footer \"Why does the login test fail?\"")
+}
+
+// newScanOptions builds scanner options from the scan flags
+func newScanOptions(cmd *cobra.Command) (*internal.ScanOptions, error) {
+	if err := internal.ValidateLanguages(languageNames); err != nil {
+		return nil, err
+	}
+	for _, class := range skipClasses {
+		if !isFileClass(class) {
+			return nil, fmt.Errorf("unknown file class %q (available: %s)",
+				class, strings.Join(internal.FileClasses(), ", "))
+		}
+	}
+
+	return &internal.ScanOptions{
+		IncludePatterns: includePatterns,
+		ExcludePatterns: excludePatterns,
+		MaxTokens:       maxTokens,
+		Progress:        newProgressFunc(cmd),
+		Cache:           openCache(cmd),
+		Redact:          redactSecrets,
+		SkipClasses:     classFilters(),
+		OnlyClasses:     onlyClasses(),
+		Languages:       languageNames,
+	}, nil
+}
+
+func isFileClass(class string) bool {
+	for _, known := range internal.FileClasses() {
+		if class == known {
+			return true
+		}
+	}
+	return false
+}
+
+// classFilters collects the classes skipped by --no-tests, --no-generated
+// and --skip-class
+func classFilters() []string {
+	classes := append([]string{}, skipClasses...)
+	if noTests {
+		classes = append(classes, internal.ClassTest)
+	}
+	if noGenerated {
+		classes = append(classes, internal.ClassGenerated)
+	}
+	return classes
+}
+
+func onlyClasses() []string {
+	if onlyTests {
+		return []string{internal.ClassTest}
+	}
+	return nil
+}
+
+// newOutputOptions builds formatter options from the output flags
+func newOutputOptions() (*internal.OutputOptions, error) {
+	if err := internal.ValidateFormat(outputFormat); err != nil {
+		return nil, err
+	}
+
+	options := &internal.OutputOptions{
+		ShowTokens:        showTokens,
+		ShowTree:          !noTree,
+		Format:            outputFormat,
+		LineNumbers:       lineNumbers,
+		TreeDepth:         treeDepth,
+		CollapseThreshold: collapseThreshold,
+	}
+
+	var err error
+	if options.Prompt, err = newPrompt(); err != nil {
+		return nil, err
+	}
+	if templateFile != "" {
+		text, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		if options.Template, err = internal.ParseTemplate(filepath.Base(templateFile), string(text)); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// newPrompt builds the prompt from --prompt, --prompt-header and
+// --prompt-footer, nil when none is set
+func newPrompt() (*internal.Prompt, error) {
+	prompt := &internal.Prompt{}
+	if promptName != "" {
+		presets, err := loadPrompts()
+		if err != nil {
+			return nil, err
+		}
+		preset, ok := presets[promptName]
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt %q (available: %s)",
+				promptName, strings.Join(internal.PromptNames(presets), ", "))
+		}
+		prompt = &preset
+	}
+
+	var err error
+	if promptHeader != "" {
+		if prompt.Header, err = internal.ReadPromptText(promptHeader); err != nil {
+			return nil, fmt.Errorf("failed to read prompt header: %w", err)
+		}
+	}
+	if promptFooter != "" {
+		if prompt.Footer, err = internal.ReadPromptText(promptFooter); err != nil {
+			return nil, fmt.Errorf("failed to read prompt footer: %w", err)
+		}
+	}
+
+	if prompt.Header == "" && prompt.Footer == "" {
+		return nil, nil
+	}
+	return prompt, nil
+}
+
+// loadPrompts returns the built-in prompt presets and those of the user
+// config
+func loadPrompts() (map[string]internal.Prompt, error) {
+	dir, err := internal.DefaultConfigDir()
+	if err != nil {
+		// Without a config dir only the built-in presets are available
+		dir = ""
+	}
+	return internal.LoadPrompts(dir)
+}
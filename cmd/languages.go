@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var languagesCmd = &cobra.Command{
+	Use:   "languages",
+	Short: "List the languages that can be detected and used with --lang",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "LANGUAGE\tALIASES\tFILES")
+
+		for _, lang := range internal.Languages() {
+			files := append(append([]string{}, lang.Extensions...), lang.Filenames...)
+			fmt.Fprintf(out, "%s\t%s\t%s\n", lang.Name, strings.Join(lang.Aliases, ", "), strings.Join(files, " "))
+		}
+		out.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(languagesCmd)
+}
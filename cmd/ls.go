@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var lsNull bool
+
+var lsCmd = &cobra.Command{
+	Use:   "ls <folder>",
+	Short: "List the files a scan would include",
+	Long: `List the files of a folder, archive or git ref that a scan with the same
+flags would include, one path per line relative to the folder.
+
+Files are only read for --tokens, which prints a table with their token
+counts instead.`,
+	Example: `  code2txt ls . --no-tests                # What would be dumped
+  code2txt ls . -0 | xargs -0 wc -l        # Pipe paths safely
+  code2txt ls ./src --tokens               # With token counts`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := scanListing(cmd, args[0], showTokens)
+		if err != nil {
+			return err
+		}
+
+		if showTokens {
+			formatter := internal.NewOutputFormatter(&internal.OutputOptions{ShowTokens: true})
+			fmt.Fprint(cmd.OutOrStdout(), formatter.FormatFileList(result))
+			return nil
+		}
+
+		separator := "\n"
+		if lsNull {
+			separator = "\x00"
+		}
+		for _, path := range internal.FilePaths(result) {
+			fmt.Fprint(cmd.OutOrStdout(), path+separator)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addListingFlags(lsCmd)
+
+	lsCmd.Flags().BoolVarP(&lsNull, "null", "0", false,
+		"Separate paths with NUL instead of newline, for xargs -0")
+	lsCmd.MarkFlagsMutuallyExclusive("null", "tokens")
+
+	rootCmd.AddCommand(lsCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var mcpNoRedact bool
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp [folder]",
+	Short: "Run a Model Context Protocol server over stdio",
+	Long: `Run a Model Context Protocol (MCP) server on stdin/stdout so AI agents can
+pull repository context on demand.
+
+Tools:
+  get_tree     Directory tree with token counts
+  read_files   Contents of files or directories, limited by a token budget
+  search       Matching lines as path:line: text
+  stats        File, directory and token totals
+
+The same include, exclude and .gitignore rules apply as for a normal scan.
+Credential files are skipped and secrets are masked unless --no-redact is
+given. Nothing outside the folder is ever read.
+
+Example client configuration:
+  {"command": "code2txt", "args": ["mcp", "/path/to/repo"]}`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		folderPath := "."
+		if len(args) == 1 {
+			folderPath = args[0]
+		}
+		if info, err := os.Stat(folderPath); err != nil || !info.IsDir() {
+			return fmt.Errorf("folder does not exist: %s", folderPath)
+		}
+
+		options, err := newScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		options.Redact = !mcpNoRedact
+
+		// stdout carries the protocol, so status messages go to stderr only
+		fmt.Fprintf(statusWriter(cmd), "code2txt MCP server for %s ready on stdio\n", folderPath)
+
+		server := internal.NewMCPServer(folderPath, options, buildInfo.Version)
+		return server.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	addScanFlags(mcpCmd)
+	// Redaction is on by default here, see --no-redact
+	mcpCmd.Flags().MarkHidden("redact")
+
+	mcpCmd.Flags().BoolVar(&mcpNoRedact, "no-redact", false,
+		"Do not skip credential files or mask secrets")
+
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+// newProgressFunc returns the progress callback for a scan, or nil when
+// progress output is disabled
+func newProgressFunc(cmd *cobra.Command) internal.ProgressFunc {
+	if quiet || noProgress {
+		return nil
+	}
+
+	w := cmd.ErrOrStderr()
+	reporter := internal.NewProgressReporter(w, isTerminal(w))
+	return reporter.Report
+}
+
+// isTerminal reports whether w is attached to a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
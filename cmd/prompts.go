@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List the prompt presets that can be used with --prompt",
+	Long: `List the prompt presets that can be used with --prompt.
+
+A preset is a header written before the dump and a footer written after it.
+Add or override presets in prompts.json inside the config directory
+($CODE2TXT_CONFIG_DIR, or code2txt in the user config directory):
+
+  {
+    "security": {
+      "header": "You are auditing this codebase for security issues.",
+      "footer": "List every vulnerability with its file and line."
+    }
+  }`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		presets, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "PROMPT\tTOKENS\tFOOTER")
+		for _, name := range internal.PromptNames(presets) {
+			prompt := presets[name]
+			fmt.Fprintf(out, "%s\t%d\t%s\n", name, prompt.Tokens(), prompt.Footer)
+		}
+		out.Flush()
+
+		if dir, err := internal.DefaultConfigDir(); err == nil {
+			fmt.Fprintf(statusWriter(cmd), "\nCustom presets: %s\n", filepath.Join(dir, "prompts.json"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promptsCmd)
+}
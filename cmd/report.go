@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var reportOutput string
+
+var reportCmd = &cobra.Command{
+	Use:   "report <folder>",
+	Short: "Write a visual token report as a standalone HTML page",
+	Long: `Scan a folder, archive or git ref and write a single HTML page showing
+where the tokens go: a treemap of the directory tree, a sortable table of
+the files, a breakdown by language and the list of excluded files with the
+reason they were left out.
+
+The page has its styles, script and data inline and needs no network
+access, so it can be opened offline or shared as one file.`,
+	Example: `  code2txt report . -o report.html
+  code2txt report ./src --no-tests -o src.html`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options, err := newScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		var skipped []internal.SkippedFile
+		options.Skipped = func(relPath, reason string) {
+			skipped = append(skipped, internal.SkippedFile{Path: relPath, Reason: reason})
+		}
+
+		source, err := internal.OpenSource(cmd.Context(), args[0], gitRef)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		result, err := internal.NewScanner(options).ScanSource(cmd.Context(), source)
+		if err != nil {
+			return scanError(err)
+		}
+
+		var page strings.Builder
+		if err := internal.WriteReport(&page, internal.NewReport(result, skipped)); err != nil {
+			return fmt.Errorf("failed to render report: %w", err)
+		}
+
+		if reportOutput == "" {
+			fmt.Fprint(cmd.OutOrStdout(), page.String())
+			return nil
+		}
+		if err := internal.WriteFileAtomic(reportOutput, []byte(page.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Fprintf(statusWriter(cmd), "Report written to: %s\n", reportOutput)
+		return nil
+	},
+}
+
+func init() {
+	addScanFlags(reportCmd)
+
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "",
+		"Save the report to this file instead of printing it\n"+
+			"Example: -o report.html")
+
+	reportCmd.Flags().StringVar(&gitRef, "ref", "",
+		"Report on a branch, tag or commit of a git repository without checking it out")
+
+	rootCmd.AddCommand(reportCmd)
+}
@@ -1,20 +1,36 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/nav9v/code2txt/internal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	outputFile      string
-	includePatterns []string
-	excludePatterns []string
-	showTokens      bool
-	noTree          bool
-	maxTokens       int
+	outputFile    string
+	copyOutput    bool
+	clipboardName string
+	quiet         bool
+	noProgress    bool
+	timeout       time.Duration
+	partialOutput bool
+	tokenBudget   int
+	splitTokens   int
+	gitRef        string
+	filesFrom     string
+	entryFiles    []string
+	reverseDeps   bool
+	manifestFile  string
 )
 
 var rootCmd = &cobra.Command{
@@ -30,53 +46,140 @@ Features:
   • Accurate token counting for AI models
   • Beautiful tree structure visualization
   • Respects .gitignore files automatically
+  • Reads .zip and .tar(.gz) archives and git refs without extracting
   • Cross-platform support (Windows, Mac, Linux)
 
 Examples:
   code2txt ./my-project                    # Scan project, output to console
   code2txt ./src --tokens                  # Show token counts for each file
   code2txt ./app -o analysis.txt           # Save output to file
+  code2txt ./app --copy                    # Copy output to the clipboard
   code2txt ./code -i "*.go,*.js"           # Only include Go and JS files
-  code2txt ./proj -e "*.log,node_modules"  # Exclude logs and dependencies`,
-	Args: cobra.ExactArgs(1),
+  code2txt ./proj -e "*.log,node_modules"  # Exclude logs and dependencies
+  code2txt release-1.2.tar.gz              # Scan an archive in place
+  code2txt ./repo.git --ref v1.2           # Scan a tag of a (bare) git repo
+  code2txt ./api ./shared                  # Combine several folders
+  git ls-files -z | code2txt --files-from -  # Dump an exact list of files
+  code2txt . --entry cmd/server/main.go    # A Go file and what it imports
+  code2txt ./src -n -f markdown            # Number lines for precise references
+  code2txt ./src --template review.tmpl    # Custom layout (see 'code2txt template')
+  code2txt ./src --prompt find-bugs        # Wrap the dump in a prompt preset
+  code2txt . --split-tokens 100000 -o ctx.txt  # ctx.part1.txt, ctx.part2.txt, ...`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && filesFrom == "" {
+			return fmt.Errorf("requires a folder, an archive or --files-from")
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		folderPath := args[0]
+		printBanner(cmd)
+		if splitTokens > 0 && outputFile == "" {
+			return fmt.Errorf("--split-tokens requires an output file (-o)")
+		}
+		if splitTokens > 0 && (copyOutput || manifestFile != "") {
+			return fmt.Errorf("--split-tokens cannot be used with --copy or --manifest")
+		}
 
-		// Validate folder exists
-		if _, err := os.Stat(folderPath); os.IsNotExist(err) {
-			return fmt.Errorf("folder does not exist: %s", folderPath)
+		outputOptions, err := newOutputOptions()
+		if err != nil {
+			return err
 		}
 
 		// Create scanner with options
-		scanner := internal.NewScanner(&internal.ScanOptions{
-			IncludePatterns: includePatterns,
-			ExcludePatterns: excludePatterns,
-			MaxTokens:       maxTokens,
-		})
-
-		// Scan the directory
-		result, err := scanner.ScanDirectory(folderPath)
+		options, err := newScanOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to scan directory: %w", err)
+			return err
+		}
+		if len(entryFiles) > 0 {
+			if err := selectGoClosure(cmd, options, args); err != nil {
+				return err
+			}
+		}
+		scanner := internal.NewScanner(options)
+
+		// Scan the directory, stopping on Ctrl-C or when the timeout expires
+		ctx := cmd.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		result, err := scanArgs(ctx, cmd, scanner, args)
+		if err != nil {
+			if result == nil {
+				// Reading a git ref can time out before the scan starts
+				if ctx.Err() != nil {
+					return scanError(err)
+				}
+				return err
+			}
+			err = scanError(err)
+			if !result.Incomplete || !partialOutput {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd), "Warning: %v, writing partial output\n", err)
+		}
+
+		// Create output formatter; with line numbers the budget counts the
+		// numbered content
+		formatter := internal.NewOutputFormatter(outputOptions)
+		result = formatter.Prepare(result)
+
+		// Trim the result to the token budget, leaving room for the prompt
+		fileBudget := tokenBudget
+		if reserved := outputOptions.Prompt.Tokens(); tokenBudget > 0 && reserved > 0 {
+			if reserved >= tokenBudget {
+				return fmt.Errorf("the prompt alone uses %d tokens, the budget is %d", reserved, tokenBudget)
+			}
+			fileBudget -= reserved
+		}
+		if omitted := internal.ApplyTokenBudget(result, fileBudget); len(omitted) > 0 {
+			fmt.Fprintf(statusWriter(cmd), "Token budget: omitted %d files to stay within %d tokens\n",
+				len(omitted), tokenBudget)
+		}
+
+		if splitTokens > 0 {
+			parts, err := formatter.Split(result, splitTokens)
+			if err != nil {
+				return err
+			}
+			if len(parts) > 1 {
+				return writeParts(cmd, formatter, parts)
+			}
 		}
 
-		// Create output formatter
-		formatter := internal.NewOutputFormatter(&internal.OutputOptions{
-			ShowTokens: showTokens,
-			ShowTree:   !noTree,
-		})
+		// Generate output; a custom template can fail while rendering
+		var rendered strings.Builder
+		if err := formatter.WriteOutput(&rendered, result); err != nil {
+			return fmt.Errorf("failed to render output: %w", err)
+		}
+		output := rendered.String()
 
-		// Generate output
-		output := formatter.FormatOutput(result)
+		if manifestFile != "" {
+			if err := writeManifest(cmd, args, result, output); err != nil {
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			fmt.Fprintf(statusWriter(cmd), "Manifest written to: %s\n", manifestFile)
+		}
 
-		// Write to file or stdout
+		// Write to file, clipboard or stdout
 		if outputFile != "" {
-			if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+			if err := internal.WriteFileAtomic(outputFile, []byte(output), 0644); err != nil {
 				return fmt.Errorf("failed to write output file: %w", err)
 			}
-			fmt.Printf("Output written to: %s\n", outputFile)
-		} else {
-			fmt.Print(output)
+			fmt.Fprintf(statusWriter(cmd), "Output written to: %s\n", outputFile)
+		}
+		if copyOutput {
+			backend, err := copyToClipboard(output)
+			if err != nil {
+				return fmt.Errorf("failed to copy output: %w", err)
+			}
+			fmt.Fprint(statusWriter(cmd), formatter.FormatSummary(result))
+			fmt.Fprintf(statusWriter(cmd), "Copied to clipboard via %s\n", backend)
+		}
+		if outputFile == "" && !copyOutput {
+			fmt.Fprint(cmd.OutOrStdout(), output)
 		}
 
 		return nil
@@ -84,31 +187,227 @@ Examples:
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false,
+		"Suppress the banner, progress and status messages\n"+
+			"Only the output itself is written to stdout")
+
 	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "",
 		"Save output to file instead of printing to console\n"+
 			"Example: -o report.txt")
 
-	rootCmd.Flags().StringSliceVarP(&includePatterns, "include", "i", []string{},
-		"Only include files matching these patterns (comma-separated)\n"+
-			"Example: -i \"*.go,*.js,*.py\" (only Go, JavaScript, Python files)")
+	rootCmd.Flags().BoolVarP(&copyOutput, "copy", "c", false,
+		"Copy output to the system clipboard instead of printing it\n"+
+			"Uses wl-copy, xclip, xsel, pbcopy or OSC 52 over SSH")
+
+	rootCmd.Flags().StringVar(&clipboardName, "clipboard", "auto",
+		"Clipboard backend used by --copy\n"+
+			"One of: "+strings.Join(internal.ClipboardBackends(), ", "))
+
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false,
+		"Do not report scan progress on stderr")
+
+	addScanFlags(rootCmd)
+	addOutputFlags(rootCmd)
+
+	rootCmd.Flags().StringVar(&gitRef, "ref", "",
+		"Scan a branch, tag or commit of a git repository without checking it out\n"+
+			"Example: --ref v1.2.0 (bare repositories default to HEAD)")
+
+	rootCmd.Flags().IntVar(&tokenBudget, "budget", 0,
+		"Keep files in path order until the total reaches N tokens (0 = no limit)\n"+
+			"Example: --budget 100000 (fit a 100k context window)")
+
+	rootCmd.Flags().IntVar(&splitTokens, "split-tokens", 0,
+		"Split the output into parts of at most N tokens, prompt included, written\n"+
+			"to numbered files next to -o, e.g. context.part1.txt (0 = no split)\n"+
+			"Files larger than a part are cut at line boundaries")
+
+	rootCmd.Flags().StringVar(&filesFrom, "files-from", "",
+		"Also include the files listed in this file, or stdin for -\n"+
+			"Paths are separated by newlines or NULs (git ls-files -z, rg -l)")
 
-	rootCmd.Flags().StringSliceVarP(&excludePatterns, "exclude", "e", []string{},
-		"Exclude files/folders matching these patterns (comma-separated)\n"+
-			"Example: -e \"*.log,node_modules,target,dist\" (skip logs & build dirs)")
+	rootCmd.Flags().StringSliceVar(&entryFiles, "entry", nil,
+		"Only include this Go file, relative to the folder, and the module packages\n"+
+			"it imports, transitively, leaving out files excluded by build constraints\n"+
+			"Example: --entry cmd/server/main.go (repeatable)")
 
-	rootCmd.Flags().BoolVar(&showTokens, "tokens", false,
-		"Display estimated token count for each file and total\n"+
-			"Useful for estimating AI model costs (GPT-4, Claude, etc.)")
+	rootCmd.Flags().BoolVar(&reverseDeps, "reverse-deps", false,
+		"With --entry, also include the packages that import the entry packages")
 
-	rootCmd.Flags().BoolVar(&noTree, "no-tree", false,
-		"Skip the directory tree visualization in output\n"+
-			"Only show file contents (faster for large projects)")
+	rootCmd.Flags().StringVar(&manifestFile, "manifest", "",
+		"Write a JSON manifest with the hash, size and tokens of every file, the git\n"+
+			"commit and the options used; check it later with 'code2txt verify'")
 
-	rootCmd.Flags().IntVar(&maxTokens, "max-tokens", 0,
-		"Skip files larger than N tokens (0 = no limit)\n"+
-			"Example: --max-tokens 5000 (skip files over 5k tokens)")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0,
+		"Stop scanning after this duration (0 = no limit)\n"+
+			"Example: --timeout 30s")
+
+	rootCmd.Flags().BoolVar(&partialOutput, "partial", false,
+		"When interrupted or timed out, still write the files gathered so far\n"+
+			"The output is clearly marked as incomplete")
+}
+
+// scanArgs scans a single folder, archive or git ref, or combines several
+// folders and the --files-from list into one result. A nil result means
+// nothing was scanned.
+func scanArgs(ctx context.Context, cmd *cobra.Command, scanner *internal.Scanner, args []string) (*internal.ScanResult, error) {
+	if len(args) == 1 && filesFrom == "" {
+		// Open the folder, archive or git ref
+		source, err := internal.OpenSource(ctx, args[0], gitRef)
+		if err != nil {
+			return nil, err
+		}
+		defer source.Close()
+		return scanner.ScanSource(ctx, source)
+	}
+
+	if gitRef != "" {
+		return nil, fmt.Errorf("--ref can only be used with a single repository")
+	}
+
+	var files []string
+	if filesFrom != "" {
+		input := cmd.InOrStdin()
+		if filesFrom != "-" {
+			file, err := os.Open(filesFrom)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file list: %w", err)
+			}
+			defer file.Close()
+			input = file
+		}
+
+		var err error
+		if files, err = internal.ReadFileList(input); err != nil {
+			return nil, fmt.Errorf("failed to read file list: %w", err)
+		}
+		if len(files) == 0 && len(args) == 0 {
+			return nil, fmt.Errorf("file list is empty: %s", filesFrom)
+		}
+	}
+
+	return scanner.ScanRoots(ctx, args, files)
+}
+
+// writeParts renders every part of a split dump to its own file next to
+// the output file
+func writeParts(cmd *cobra.Command, formatter *internal.OutputFormatter, parts []*internal.ScanResult) error {
+	// Render everything first, so a template error leaves no partial set
+	outputs := make([]string, len(parts))
+	for i, part := range parts {
+		var rendered strings.Builder
+		if err := formatter.WriteOutput(&rendered, part); err != nil {
+			return fmt.Errorf("failed to render part %d: %w", i+1, err)
+		}
+		outputs[i] = rendered.String()
+	}
+
+	for i, output := range outputs {
+		path := partFileName(outputFile, i+1)
+		if err := internal.WriteFileAtomic(path, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(statusWriter(cmd), "Output written to: %s (part %d of %d)\n", path, i+1, len(parts))
+	}
+	return nil
+}
+
+// partFileName inserts the part number before the extension of path, e.g.
+// context.part2.txt
+func partFileName(path string, part int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(path, ext), part, ext)
+}
+
+// selectGoClosure limits the scan to the --entry files and their Go
+// dependencies inside the scanned folder
+func selectGoClosure(cmd *cobra.Command, options *internal.ScanOptions, args []string) error {
+	if len(args) != 1 || filesFrom != "" || gitRef != "" {
+		return fmt.Errorf("--entry can only be used with a single folder")
+	}
+
+	roles, err := internal.GoClosure(args[0], entryFiles, reverseDeps)
+	if err != nil {
+		return err
+	}
+
+	root, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+
+	options.Only = make(map[string]string)
+	outside := 0
+	for path, role := range roles {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			outside++
+			continue
+		}
+		options.Only[filepath.ToSlash(relPath)] = role
+	}
+
+	if len(options.Only) == 0 {
+		return fmt.Errorf("the entry files are not inside %s", args[0])
+	}
+	fmt.Fprintf(statusWriter(cmd), "Entry closure: %d files", len(options.Only))
+	if outside > 0 {
+		fmt.Fprintf(statusWriter(cmd), " (%d outside %s left out)", outside, args[0])
+	}
+	fmt.Fprintln(statusWriter(cmd))
+	return nil
+}
+
+// manifestSkipFlags do not change the content of the output and are left
+// out of the manifest
+var manifestSkipFlags = map[string]bool{
+	"output": true, "manifest": true, "copy": true, "clipboard": true,
+	"quiet": true, "no-progress": true, "no-cache": true,
+}
+
+// writeManifest records output, rendered from result, in the --manifest
+// file
+func writeManifest(cmd *cobra.Command, args []string, result *internal.ScanResult, output string) error {
+	manifest := internal.NewManifest(result, []byte(output))
+	manifest.Version = buildInfo.Version
+	manifest.Redacted = redactSecrets
+
+	gitPath := result.RootPath
+	if len(args) == 1 {
+		gitPath = args[0]
+	}
+	manifest.Git = internal.ReadGitState(gitPath, gitRef)
+
+	manifest.Options = make(map[string]string)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if !manifestSkipFlags[flag.Name] {
+			manifest.Options[flag.Name] = flag.Value.String()
+		}
+	})
+
+	return internal.WriteManifest(manifestFile, manifest)
+}
+
+// scanError describes why a scan stopped
+func scanError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("scan timed out after %s", timeout)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("scan interrupted")
+	default:
+		return fmt.Errorf("failed to scan directory: %w", err)
+	}
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	// Cancel the scan on the first Ctrl-C; a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return rootCmd.ExecuteContext(ctx)
 }
@@ -1,11 +1,29 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/nav9v/code2txt/internal"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the scan cache out of the user's cache directory
+	cacheDir, err := os.MkdirTemp("", "code2txt-cache")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("CODE2TXT_CACHE_DIR", cacheDir)
+
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
 func TestRootCommand(t *testing.T) {
 	// Test that the command can be executed without errors
 	cmd := rootCmd
@@ -67,3 +85,269 @@ func main() {
 		t.Errorf("Expected no error for valid folder, got: %v", err)
 	}
 }
+
+func TestStdoutContainsOnlyOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+
+	rootCmd.SetArgs([]string{tempDir})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !strings.HasPrefix(stdout.String(), "Directory Structure:") {
+		t.Errorf("Expected stdout to start with the output, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "version") {
+		t.Errorf("Expected banner on stderr, got %q", stderr.String())
+	}
+
+	// Quiet mode hides the banner entirely
+	stdout.Reset()
+	stderr.Reset()
+	rootCmd.SetArgs([]string{tempDir, "--quiet"})
+	defer func() { quiet = false }()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected no stderr output in quiet mode, got %q", stderr.String())
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	SetVersionInfo("v1.2.3", "abc123", "2024-01-01T00:00:00Z")
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+
+	rootCmd.SetArgs([]string{"version"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, expected := range []string{"v1.2.3", "abc123", "2024-01-01T00:00:00Z", runtime.Version()} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Expected version output to contain %q, got %q", expected, stdout.String())
+		}
+	}
+}
+
+func TestTimeoutKeepsExistingOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	outFile := filepath.Join(t.TempDir(), "dump.txt")
+	if err := os.WriteFile(outFile, []byte("previous dump"), 0644); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+	defer func() {
+		timeout = 0
+		partialOutput = false
+		outputFile = ""
+		quiet = false
+	}()
+
+	rootCmd.SetArgs([]string{tempDir, "-q", "-o", outFile, "--timeout", "1ns"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if data, _ := os.ReadFile(outFile); string(data) != "previous dump" {
+		t.Errorf("Expected previous dump to be kept, got %q", data)
+	}
+
+	// With --partial the gathered output is written with a marker
+	rootCmd.SetArgs([]string{tempDir, "-q", "-o", outFile, "--timeout", "1ns", "--partial"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error with --partial, got %v", err)
+	}
+	if data, _ := os.ReadFile(outFile); !strings.Contains(string(data), "INCOMPLETE OUTPUT") {
+		t.Errorf("Expected incomplete marker in output, got %q", data)
+	}
+}
+
+func TestCacheCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	t.Setenv("CODE2TXT_CACHE_DIR", t.TempDir())
+
+	rootCmd.SetArgs([]string{tempDir, "-q"})
+	defer func() { quiet = false }()
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+
+	rootCmd.SetArgs([]string{"cache", "stats"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Entries: 1") {
+		t.Errorf("Expected one cache entry, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"cache", "clear"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	rootCmd.SetArgs([]string{"cache", "stats"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Entries: 0") {
+		t.Errorf("Expected empty cache after clear, got %q", stdout.String())
+	}
+}
+
+func TestFilesFromStdin(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{"a.go": "package a\n", "b.go": "package b\n", "c.go": "package c\n"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	list := filepath.Join(tempDir, "a.go") + "\x00" + filepath.Join(tempDir, "c.go") + "\x00" +
+		filepath.Join(tempDir, "a.go") + "\x00"
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetIn(strings.NewReader(list))
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetIn(nil)
+	defer func() {
+		filesFrom = ""
+		quiet = false
+	}()
+
+	rootCmd.SetArgs([]string{"--files-from", "-", "-q"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := stdout.String()
+	if strings.Count(output, "File: a.go") != 1 || !strings.Contains(output, "File: c.go") {
+		t.Errorf("Expected a.go once and c.go, got %q", output)
+	}
+	if strings.Contains(output, "b.go") {
+		t.Errorf("Expected unlisted b.go to be left out, got %q", output)
+	}
+}
+
+func TestListingCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "src", "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create test folder: %v", err)
+	}
+	for _, name := range []string{"main.go", filepath.Join("src", "a.go"), filepath.Join("src", "sub", "b.go")} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("package x\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	defer rootCmd.SetOut(nil)
+	defer func() {
+		lsNull = false
+		treeDepth = 0
+		treeDirsOnly = false
+		quiet = false
+	}()
+
+	rootCmd.SetArgs([]string{"ls", tempDir, "-0", "-q"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := "main.go\x00" + filepath.Join("src", "a.go") + "\x00" + filepath.Join("src", "sub", "b.go") + "\x00"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	rootCmd.SetArgs([]string{"tree", tempDir, "-d", "-L", "1", "-q"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if output := stdout.String(); !strings.Contains(output, "└── src\n") || strings.Contains(output, "sub") ||
+		strings.Contains(output, "main.go") || !strings.Contains(output, "3 directories, 3 files") {
+		t.Errorf("Expected only the top-level folders, got %q", output)
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		content := strings.Repeat("func example() { return compute(1, 2, 3) }\n", 20)
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	outFile := filepath.Join(t.TempDir(), "ctx.txt")
+	defer func() {
+		outputFile = ""
+		splitTokens = 0
+		promptHeader = ""
+		quiet = false
+	}()
+
+	rootCmd.SetArgs([]string{tempDir, "-q", "-o", outFile, "--split-tokens", "400", "--prompt-header", "Review this."})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(outFile), "ctx.part*.txt"))
+	if len(matches) < 2 {
+		t.Fatalf("Expected several part files, got %v", matches)
+	}
+	for i := range matches {
+		data, err := os.ReadFile(partFileName(outFile, i+1))
+		if err != nil {
+			t.Fatalf("Missing part %d: %v", i+1, err)
+		}
+		if !strings.HasPrefix(string(data), "Review this.\n\nPart ") {
+			t.Errorf("Expected the header and part note in part %d, got %q", i+1, data)
+		}
+	}
+	if _, err := os.Stat(outFile); err == nil {
+		t.Error("Expected only part files to be written")
+	}
+}
+
+func TestWatchRenderErrorKeepsOutput(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "dump.txt")
+	if err := os.WriteFile(outFile, []byte("previous dump"), 0644); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+	defer func() { outputFile = "" }()
+	outputFile = outFile
+
+	tmpl, err := internal.ParseTemplate("broken", `{{index .Files 5}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := internal.NewOutputFormatter(&internal.OutputOptions{Template: tmpl})
+	result := &internal.ScanResult{RootPath: "project"}
+
+	if err := writeWatchOutput(formatter, result); err == nil || !strings.Contains(err.Error(), "failed to render output") {
+		t.Fatalf("Expected a render error, got %v", err)
+	}
+	if data, _ := os.ReadFile(outFile); string(data) != "previous dump" {
+		t.Errorf("Expected previous dump to be kept, got %q", data)
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr  string
+	serveRoots []string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve --root <dir> [--root name=<dir>...]",
+	Short: "Serve scans of allowlisted directories over HTTP",
+	Long: `Run a local HTTP server that exposes scans as an API.
+
+Only the directories passed with --root can be scanned. Each root is
+addressed by name (the folder name, or the name given as name=dir); requests
+may narrow the scan to a subdirectory with the path parameter, but can never
+leave the root. Scans are cancelled when the client disconnects.
+
+Endpoints (all GET):
+  /api/roots   List the available roots
+  /api/scan    Scan and return the full output (format=json by default)
+  /api/dump    Scan and return the output (format=text by default)
+  /api/tree    Directory tree as JSON
+  /api/stats   File, directory and token totals as JSON
+
+Query parameters:
+  root         Root name (optional when only one root is served)
+  path         Subdirectory relative to the root
+  include      Comma-separated include patterns
+  exclude      Comma-separated exclude patterns, added to the defaults
+  format       text, markdown, xml or json
+  budget       Keep files up to this many tokens in total
+  max_tokens   Skip files larger than this many tokens
+  tokens       Show token counts (default true)
+  tree         Include the directory tree (default true)
+
+Examples:
+  code2txt serve --root ./my-project
+  code2txt serve --addr :9000 --root api=./api --root web=./web
+  curl 'localhost:8080/api/dump?include=*.go&budget=50000'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		roots, err := parseRoots(serveRoots)
+		if err != nil {
+			return err
+		}
+
+		server, err := internal.NewServer(&internal.ServerOptions{
+			Roots:  roots,
+			Cache:  openCache(cmd),
+			Redact: redactSecrets,
+		})
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", serveAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", serveAddr, err)
+		}
+
+		httpServer := &http.Server{
+			Handler:           server,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		status := statusWriter(cmd)
+		fmt.Fprintf(status, "Serving %d root(s) on http://%s (Ctrl-C to stop)\n", len(roots), listener.Addr())
+		for name, dir := range roots {
+			fmt.Fprintf(status, "  %s: %s\n", name, dir)
+		}
+
+		// Shut down gracefully on Ctrl-C
+		ctx := cmd.Context()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}()
+
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// parseRoots turns name=dir or dir arguments into a map of root names
+func parseRoots(args []string) (map[string]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("at least one --root is required")
+	}
+
+	roots := make(map[string]string)
+	for _, arg := range args {
+		name, dir, found := strings.Cut(arg, "=")
+		if !found {
+			dir = arg
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, err
+			}
+			name = filepath.Base(abs)
+		}
+		if name == "" || dir == "" {
+			return nil, fmt.Errorf("invalid root %q, expected <dir> or <name>=<dir>", arg)
+		}
+		if _, exists := roots[name]; exists {
+			return nil, fmt.Errorf("duplicate root name %q, use <name>=<dir> to disambiguate", name)
+		}
+		roots[name] = dir
+	}
+	return roots, nil
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080",
+		"Address to listen on\n"+
+			"Example: --addr :9000 (all interfaces, port 9000)")
+
+	serveCmd.Flags().StringArrayVar(&serveRoots, "root", nil,
+		"Directory that may be scanned, as <dir> or <name>=<dir> (repeatable)")
+
+	addCacheFlag(serveCmd)
+
+	serveCmd.Flags().BoolVar(&redactSecrets, "redact", false,
+		"Skip credential files and mask secrets in every response")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary <folder>",
+	Short: "Print file, directory and size totals of a scan",
+	Long: `Print the number of files and directories, and their total size, that a
+scan of a folder, archive or git ref with the same flags would include.
+
+With --tokens the files are read and the token total is added.`,
+	Example: `  code2txt summary .
+  code2txt summary ./src --tokens -i "*.go"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := scanListing(cmd, args[0], showTokens)
+		if err != nil {
+			return err
+		}
+
+		formatter := internal.NewOutputFormatter(&internal.OutputOptions{ShowTokens: showTokens})
+		fmt.Fprint(cmd.OutOrStdout(), formatter.FormatSummary(result))
+		return nil
+	},
+}
+
+func init() {
+	addListingFlags(summaryCmd)
+	rootCmd.AddCommand(summaryCmd)
+}
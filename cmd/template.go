@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "List or export the built-in output templates",
+	Long: `List or export the built-in output templates.
+
+The text, markdown and xml formats are Go text/template files. Export one,
+edit it and pass it back with --template to change headers, rulers or the
+layout of the whole dump. Templates are executed with the scan result, the
+rendered tree and the sorted files, and can use these helpers:
+
+  language FILE   fence TEXT   indent N TEXT   tokens TEXT   humanize N
+  summary N       repeat S N   terminate TEXT  slash PATH    xml TEXT
+  xmlattr TEXT`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in templates",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), strings.Join(internal.TemplateNames(), "\n"))
+	},
+}
+
+var templateExportCmd = &cobra.Command{
+	Use:   "export <name>",
+	Short: "Print a built-in template to stdout",
+	Example: `  code2txt template export markdown > review.tmpl
+  code2txt ./src --template review.tmpl`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		text, err := internal.BuiltinTemplate(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), text)
+		return nil
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateExportCmd)
+	rootCmd.AddCommand(templateCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeDirsOnly bool
+	treeSize     bool
+	treeSort     string
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree <folder>",
+	Short: "Print the directory tree without the file contents",
+	Long: `Print the directory tree of a folder, archive or git ref, with the same
+filtering as a full scan but without the file contents.
+
+Files are only read when token counts are needed, for --tokens,
+--sort tokens or --max-tokens. With --size or --tokens, directories are
+annotated with the files, size and tokens of everything below them.`,
+	Example: `  code2txt tree . -L 2 -d                     # Top two levels of folders
+  code2txt tree ./src --size --sort size      # Largest entries first
+  code2txt tree ./src --tokens --sort tokens  # Where the tokens go`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := internal.ValidateTreeSort(treeSort); err != nil {
+			return err
+		}
+
+		result, err := scanListing(cmd, args[0], showTokens || treeSort == internal.TreeSortTokens)
+		if err != nil {
+			return err
+		}
+
+		tree := internal.BuildTree(result)
+		internal.SortTree(tree, treeSort)
+		fmt.Fprint(cmd.OutOrStdout(), internal.RenderTreeOptions(tree, internal.TreeOptions{
+			ShowTokens: showTokens,
+			ShowSize:   treeSize,
+			MaxDepth:   treeDepth,
+			DirsOnly:   treeDirsOnly,
+			Collapse:   collapseThreshold,
+		}))
+
+		files, directories := internal.GetTreeStats(tree)
+		fmt.Fprintf(cmd.OutOrStdout(), "\n%d directories, %d files\n", directories, files)
+		return nil
+	},
+}
+
+// addListingFlags registers the flags shared by tree, ls and summary
+func addListingFlags(cmd *cobra.Command) {
+	addScanFlags(cmd)
+
+	cmd.Flags().BoolVar(&showTokens, "tokens", false,
+		"Read the files and show their token counts")
+
+	cmd.Flags().StringVar(&gitRef, "ref", "",
+		"List a branch, tag or commit of a git repository without checking it out")
+}
+
+// scanListing scans the folder, archive or git ref of the tree, ls and
+// summary commands. File contents are only read when withTokens is set, or
+// when --max-tokens needs the counts.
+func scanListing(cmd *cobra.Command, path string, withTokens bool) (*internal.ScanResult, error) {
+	options, err := newScanOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+	options.SkipContent = !withTokens && maxTokens == 0
+
+	source, err := internal.OpenSource(cmd.Context(), path, gitRef)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	result, err := internal.NewScanner(options).ScanSource(cmd.Context(), source)
+	if err != nil {
+		return nil, scanError(err)
+	}
+	return result, nil
+}
+
+func init() {
+	addListingFlags(treeCmd)
+
+	treeCmd.Flags().IntVarP(&treeDepth, "depth", "L", 0,
+		"Only show N levels below the folder (0 = no limit)")
+
+	treeCmd.Flags().IntVar(&collapseThreshold, "collapse-threshold", 0,
+		"Fold the small files of directories holding more than N files into one line")
+
+	treeCmd.Flags().BoolVarP(&treeDirsOnly, "dirs-only", "d", false,
+		"Only show directories")
+
+	treeCmd.Flags().BoolVar(&treeSize, "size", false,
+		"Show the size of files and directories")
+
+	treeCmd.Flags().StringVar(&treeSort, "sort", internal.TreeSortName,
+		"Order entries by: "+strings.Join(internal.TreeSortOrders(), ", ")+"\n"+
+			"Sizes and tokens put the largest first")
+
+	rootCmd.AddCommand(treeCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	unpackDir    string
+	unpackDiff   bool
+	unpackFormat string
+)
+
+var unpackCmd = &cobra.Command{
+	Use:   "unpack <dump>",
+	Short: "Write the files of a dump back to a folder",
+	Long: `Write the files of a dump back to a folder.
+
+Reads dumps in the text, markdown, xml and json formats, for example a dump
+edited by a model, and writes every file below the output folder. Line
+numbers added with --line-numbers are removed. Absolute paths and paths
+leaving the folder are rejected before anything is written. Use - to read
+the dump from stdin.`,
+	Example: `  code2txt unpack reply.md -o ./my-project --diff   # Review the changes first
+  code2txt unpack reply.md -o ./my-project          # Then write them`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if unpackDir == "" {
+			return fmt.Errorf("unpack requires an output folder (-o)")
+		}
+		if err := internal.ValidateFormat(unpackFormat); err != nil {
+			return err
+		}
+
+		var dump []byte
+		var err error
+		if args[0] == "-" {
+			dump, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			dump, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+
+		files, err := internal.ParseDump(string(dump), unpackFormat)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := internal.ValidateUnpackPath(file.Path); err != nil {
+				return err
+			}
+		}
+
+		if unpackDiff {
+			return diffUnpacked(cmd.OutOrStdout(), files)
+		}
+
+		if err := internal.WriteUnpacked(unpackDir, files); err != nil {
+			return fmt.Errorf("failed to unpack: %w", err)
+		}
+		fmt.Fprintf(statusWriter(cmd), "Unpacked %d files to %s\n", len(files), unpackDir)
+		return nil
+	},
+}
+
+// diffUnpacked writes a unified diff from the files in the output folder
+// to the unpacked files
+func diffUnpacked(out io.Writer, files []internal.UnpackedFile) error {
+	for _, file := range files {
+		oldName := "a/" + file.Path
+		current, err := os.ReadFile(filepath.Join(unpackDir, filepath.FromSlash(file.Path)))
+		if errors.Is(err, fs.ErrNotExist) {
+			oldName = "/dev/null"
+		} else if err != nil {
+			return err
+		}
+
+		fmt.Fprint(out, internal.UnifiedDiff(oldName, "b/"+file.Path, string(current), file.Content))
+	}
+	return nil
+}
+
+func init() {
+	unpackCmd.Flags().StringVarP(&unpackDir, "output", "o", "",
+		"Folder to write the files to (created if needed)")
+	unpackCmd.Flags().BoolVar(&unpackDiff, "diff", false,
+		"Show a unified diff against the files in the output folder instead of writing")
+	unpackCmd.Flags().StringVarP(&unpackFormat, "format", "f", "",
+		"Format of the dump: "+strings.Join(internal.OutputFormats(), ", ")+" (detected by default)")
+	rootCmd.AddCommand(unpackCmd)
+}
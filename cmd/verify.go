@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyManifest string
+	verifyRef      string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <dump> <folder>",
+	Short: "Check a dump and its manifest against a folder",
+	Long: `Check a dump and its manifest against a folder.
+
+The dump must match the hash recorded in its manifest, and every file listed
+in the manifest must still have the same content in the folder. Files that
+were added to the folder since are not reported.`,
+	Example: `  code2txt ./src -o dump.txt --manifest dump.manifest.json
+  code2txt verify dump.txt ./src --manifest dump.manifest.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dumpPath, folderPath := args[0], args[1]
+		if verifyManifest == "" {
+			verifyManifest = dumpPath + ".manifest.json"
+		}
+
+		manifest, err := internal.ReadManifest(verifyManifest)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		dump, err := os.ReadFile(dumpPath)
+		if err != nil {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+
+		source, err := internal.OpenSource(cmd.Context(), folderPath, verifyRef)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+
+		problems := internal.VerifyManifest(manifest, source.FS)
+		if internal.HashContent(dump) != manifest.OutputSHA256 {
+			problems = append([]string{"modified: " + dumpPath}, problems...)
+		}
+
+		out := cmd.OutOrStdout()
+		if manifest.Git != nil {
+			if state := internal.ReadGitState(folderPath, verifyRef); state != nil && state.Commit != manifest.Git.Commit {
+				fmt.Fprintf(out, "note: the dump was made at commit %s, the folder is at %s\n",
+					manifest.Git.Commit, state.Commit)
+			}
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(out, problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("verification failed: %d differences", len(problems))
+		}
+
+		fmt.Fprintf(out, "OK: the dump and its %d files match\n", len(manifest.Files))
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyManifest, "manifest", "",
+		"Manifest written with the dump (default <dump>.manifest.json)")
+	verifyCmd.Flags().StringVar(&verifyRef, "ref", "",
+		"Check the files of a branch, tag or commit instead of the working tree")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+	Platform  string
+}
+
+var buildInfo = BuildInfo{Version: "dev"}
+
+// SetVersionInfo records the build metadata injected via ldflags. Missing
+// values fall back to the VCS information embedded by the Go toolchain.
+func SetVersionInfo(version, commit, date string) {
+	if version != "" {
+		buildInfo.Version = version
+	}
+	buildInfo.Commit = commit
+	buildInfo.Date = date
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if buildInfo.Commit == "" {
+					buildInfo.Commit = setting.Value
+				}
+			case "vcs.time":
+				if buildInfo.Date == "" {
+					buildInfo.Date = setting.Value
+				}
+			}
+		}
+	}
+
+	rootCmd.Version = buildInfo.Version
+}
+
+func getBuildInfo() BuildInfo {
+	info := buildInfo
+	info.GoVersion = runtime.Version()
+	info.Platform = runtime.GOOS + "/" + runtime.GOARCH
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		info := getBuildInfo()
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "code2txt %s\n", info.Version)
+		fmt.Fprintf(out, "  commit:     %s\n", info.Commit)
+		fmt.Fprintf(out, "  built:      %s\n", info.Date)
+		fmt.Fprintf(out, "  go version: %s\n", info.GoVersion)
+		fmt.Fprintf(out, "  platform:   %s\n", info.Platform)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
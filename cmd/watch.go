@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nav9v/code2txt/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchDebounce     time.Duration
+	watchPollInterval time.Duration
+	watchForcePoll    bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <folder> -o <file>",
+	Short: "Regenerate the output file whenever files change",
+	Long: `Scan a folder, write the output file, and keep it up to date.
+
+Changes are picked up through native filesystem notifications (inotify on
+Linux) or by polling elsewhere. Bursts of changes are debounced, only the
+affected files are read again, and the output file is replaced atomically.
+The same include, exclude and .gitignore rules apply as for a normal scan,
+and a change to .gitignore rescans the whole folder with the new rules.
+
+Examples:
+  code2txt watch . -o context.txt
+  code2txt watch ./src -o context.txt -i "*.go" --tokens`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		folderPath := args[0]
+
+		if outputFile == "" {
+			return fmt.Errorf("watch requires an output file (-o)")
+		}
+		if info, err := os.Stat(folderPath); err != nil || !info.IsDir() {
+			return fmt.Errorf("folder does not exist: %s", folderPath)
+		}
+		outputOptions, err := newOutputOptions()
+		if err != nil {
+			return err
+		}
+
+		options, err := newScanOptions(cmd)
+		if err != nil {
+			return err
+		}
+		excludeOutputFile(options, folderPath, outputFile)
+		scanner := internal.NewScanner(options)
+		formatter := internal.NewOutputFormatter(outputOptions)
+		status := statusWriter(cmd)
+
+		ctx := cmd.Context()
+		result, err := scanner.ScanDirectoryContext(ctx, folderPath)
+		if err != nil {
+			return scanError(err)
+		}
+		// Progress is only useful for the initial scan
+		options.Progress = nil
+
+		if err := writeWatchOutput(formatter, result); err != nil {
+			return err
+		}
+
+		skip := func(path string) bool {
+			relPath, err := filepath.Rel(folderPath, path)
+			return err != nil || scanner.Excludes(relPath) || isOutputPath(path, outputFile)
+		}
+
+		watcher, err := newWatcher(folderPath, skip)
+		if err != nil {
+			return err
+		}
+		defer func() { watcher.Close() }()
+
+		fmt.Fprintf(status, "Output written to: %s (%d files, %d tokens)\n",
+			outputFile, result.TotalFiles, result.TotalTokens)
+		fmt.Fprintf(status, "Watching %s for changes using %s (Ctrl-C to stop)\n",
+			folderPath, watcher.Name())
+
+		gitignorePath := filepath.Join(folderPath, ".gitignore")
+		pending := make(map[string]bool)
+		var debounce <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Fprintln(status, "Stopped watching")
+				return nil
+
+			case path := <-watcher.Events():
+				pending[path] = true
+				debounce = time.After(watchDebounce)
+
+			case err := <-watcher.Errors():
+				fmt.Fprintf(status, "Warning: %v\n", err)
+
+			case <-debounce:
+				debounce = nil
+				changed := make([]string, 0, len(pending))
+				for path := range pending {
+					changed = append(changed, path)
+				}
+				pending = make(map[string]bool)
+				sort.Strings(changed)
+
+				if err := regenerate(ctx, scanner, formatter, result, changed); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					fmt.Fprintf(status, "Warning: %v\n", err)
+					continue
+				}
+
+				// New .gitignore rules change which directories are watched
+				if containsPath(changed, gitignorePath) {
+					watcher.Close()
+					if watcher, err = newWatcher(folderPath, skip); err != nil {
+						return err
+					}
+				}
+				fmt.Fprintf(status, "[%s] %s changed, regenerated %s (%d files, %d tokens)\n",
+					time.Now().Format("15:04:05"), describeChanges(folderPath, changed),
+					outputFile, result.TotalFiles, result.TotalTokens)
+			}
+		}
+	},
+}
+
+// newWatcher watches folderPath for changes, skipping the paths for which
+// skip returns true
+func newWatcher(folderPath string, skip func(path string) bool) (internal.Watcher, error) {
+	var watcher internal.Watcher
+	var err error
+	if watchForcePoll {
+		watcher, err = internal.NewPollingWatcher(folderPath, skip, watchPollInterval)
+	} else {
+		watcher, err = internal.NewWatcher(folderPath, skip, watchPollInterval)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", folderPath, err)
+	}
+	return watcher, nil
+}
+
+// regenerate rescans the changed paths and rewrites the output file
+func regenerate(ctx context.Context, scanner *internal.Scanner, formatter *internal.OutputFormatter, result *internal.ScanResult, changed []string) error {
+	if err := scanner.RescanPaths(ctx, result, changed); err != nil {
+		return fmt.Errorf("failed to rescan: %w", err)
+	}
+	return writeWatchOutput(formatter, result)
+}
+
+// writeWatchOutput renders result and replaces the output file with it. A
+// custom template can fail while rendering, which leaves the previous
+// output file in place.
+func writeWatchOutput(formatter *internal.OutputFormatter, result *internal.ScanResult) error {
+	var rendered strings.Builder
+	if err := formatter.WriteOutput(&rendered, result); err != nil {
+		return fmt.Errorf("failed to render output: %w", err)
+	}
+	if err := internal.WriteFileAtomic(outputFile, []byte(rendered.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// describeChanges lists the changed paths relative to the watched folder
+func describeChanges(folderPath string, changed []string) string {
+	const maxListed = 5
+
+	names := make([]string, 0, len(changed))
+	for _, path := range changed {
+		if relPath, err := filepath.Rel(folderPath, path); err == nil {
+			path = relPath
+		}
+		names = append(names, path)
+	}
+
+	if len(names) > maxListed {
+		return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListed], ", "), len(names)-maxListed)
+	}
+	return strings.Join(names, ", ")
+}
+
+// excludeOutputFile keeps the output file out of its own dump when it is
+// written inside the watched folder
+func excludeOutputFile(options *internal.ScanOptions, folderPath, outputPath string) {
+	relPath, err := relativeTo(folderPath, outputPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return
+	}
+	if options.ExcludePaths == nil {
+		options.ExcludePaths = make(map[string]bool)
+	}
+	options.ExcludePaths[filepath.ToSlash(relPath)] = true
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutputPath reports whether path is the output file or one of the
+// temporary files used to replace it atomically
+func isOutputPath(path, outputPath string) bool {
+	if !sameFile(filepath.Dir(path), filepath.Dir(outputPath)) {
+		return false
+	}
+	base := filepath.Base(outputPath)
+	name := filepath.Base(path)
+	return name == base || strings.HasPrefix(name, "."+base+".tmp-")
+}
+
+func relativeTo(base, path string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, absPath)
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func init() {
+	watchCmd.Flags().StringVarP(&outputFile, "output", "o", "",
+		"File to keep up to date (required)")
+
+	addScanFlags(watchCmd)
+	addOutputFlags(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 300*time.Millisecond,
+		"Wait this long after the last change before regenerating")
+
+	watchCmd.Flags().DurationVar(&watchPollInterval, "poll-interval", time.Second,
+		"How often to check for changes when polling")
+
+	watchCmd.Flags().BoolVar(&watchForcePoll, "poll", false,
+		"Poll for changes instead of using filesystem notifications")
+
+	rootCmd.AddCommand(watchCmd)
+}
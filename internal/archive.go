@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// archiveExtensions lists the archive types that can be scanned directly
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether path names an archive that can be scanned
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive opens a .zip, .tar, .tar.gz or .tgz file as a filesystem.
+// Zip files are read in place; tar files can only be read sequentially, so
+// their contents are loaded into memory. The returned closer releases the
+// archive.
+func OpenArchive(path string) (fs.FS, io.Closer, error) {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".zip") {
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open zip archive: %w", err)
+		}
+		return reader, reader, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	fsys, err := readTar(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tar archive: %w", err)
+	}
+	return fsys, io.NopCloser(nil), nil
+}
+
+// readTar loads the regular files and directories of a tar stream into an
+// in-memory filesystem
+func readTar(r io.Reader) (*memFS, error) {
+	fsys := newMemFS()
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := archiveEntryName(header.Name)
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			fsys.MapFS[name] = &fstest.MapFile{
+				Mode:    fs.ModeDir | fs.FileMode(header.Mode).Perm(),
+				ModTime: header.ModTime,
+			}
+		case tar.TypeReg:
+			file := &fstest.MapFile{
+				Mode:    fs.FileMode(header.Mode).Perm(),
+				ModTime: header.ModTime,
+			}
+			// The scanner skips large files anyway, so don't hold them in memory
+			if header.Size > maxFileSize {
+				fsys.addStub(name, header.Size, file)
+				continue
+			}
+			if file.Data, err = io.ReadAll(tr); err != nil {
+				return nil, err
+			}
+			fsys.MapFS[name] = file
+		}
+	}
+}
+
+// memFS is the in-memory filesystem of a tar archive or git tree. Files too
+// large to scan are kept as stubs that report their size but hold no data,
+// so the scanner still reports them as too large.
+type memFS struct {
+	fstest.MapFS
+	stubs map[string]int64
+}
+
+func newMemFS() *memFS {
+	return &memFS{MapFS: fstest.MapFS{}, stubs: make(map[string]int64)}
+}
+
+// addStub adds file as name, reporting size bytes without any data
+func (m *memFS) addStub(name string, size int64, file *fstest.MapFile) {
+	m.MapFS[name] = file
+	m.stubs[name] = size
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if _, ok := m.stubs[name]; ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errTooLarge}
+	}
+	return m.MapFS.Open(name)
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	if _, ok := m.stubs[name]; ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errTooLarge}
+	}
+	return m.MapFS.ReadFile(name)
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := m.MapFS.Stat(name)
+	if size, ok := m.stubs[name]; ok && err == nil {
+		info = stubInfo{info, size}
+	}
+	return info, err
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := m.MapFS.ReadDir(name)
+	for i, entry := range entries {
+		if size, ok := m.stubs[path.Join(name, entry.Name())]; ok {
+			entries[i] = stubEntry{entry, size}
+		}
+	}
+	return entries, err
+}
+
+// errTooLarge is returned when opening a stub of memFS
+var errTooLarge = errors.New("file too large to load")
+
+// stubInfo reports the size of a stub
+type stubInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (i stubInfo) Size() int64 { return i.size }
+
+// stubEntry is the directory entry of a stub
+type stubEntry struct {
+	fs.DirEntry
+	size int64
+}
+
+func (e stubEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return stubInfo{info, e.size}, nil
+}
+
+// archiveEntryName cleans an entry name and rejects names that would
+// escape the archive root
+func archiveEntryName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if name == "." || !fs.ValidPath(name) {
+		return "", false
+	}
+	return name, true
+}
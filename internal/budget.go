@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"path/filepath"
+)
+
+// ApplyTokenBudget trims result so the total token count of its files fits
+// in budget. Files are considered in path order; a file that does not fit
+// is omitted, but smaller files after it may still be kept. Directories
+// left without files are dropped too. It returns the omitted files.
+func ApplyTokenBudget(result *ScanResult, budget int) []*FileInfo {
+	if budget <= 0 || result.TotalTokens <= budget {
+		return nil
+	}
+
+	kept := make(map[*FileInfo]bool)
+	omitted := make([]*FileInfo, 0)
+	used := 0
+	for _, file := range contentFiles(result) {
+		if used+file.TokenCount > budget {
+			omitted = append(omitted, file)
+			continue
+		}
+		used += file.TokenCount
+		kept[file] = true
+	}
+
+	// Directories that still contain a kept file
+	keptFiles := make([]*FileInfo, 0, len(kept))
+	for file := range kept {
+		keptFiles = append(keptFiles, file)
+	}
+	keptDirs := fileDirs(keptFiles)
+
+	files := make([]*FileInfo, 0, len(kept)+len(keptDirs))
+	for _, file := range result.Files {
+		if kept[file] || (file.IsDirectory && (keptDirs[file.RelativePath] || file.RelativePath == ".")) {
+			files = append(files, file)
+		}
+	}
+
+	result.Files = files
+	result.TotalFiles = len(kept)
+	result.TotalTokens = used
+	return omitted
+}
+
+// fileDirs returns the directories that lead to files, by relative path
+func fileDirs(files []*FileInfo) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file.RelativePath)
+		for dir != "." && dir != string(filepath.Separator) && !dirs[dir] {
+			dirs[dir] = true
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenizerID identifies the token estimator used by CountTokens. Cached
+// token counts from a different tokenizer are recomputed, so bump it
+// whenever CountTokens changes.
+const TokenizerID = "approx-v1"
+
+// CacheEntry is the cached result of processing a single file
+type CacheEntry struct {
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	Hash       string    `json:"hash"`
+	Tokenizer  string    `json:"tokenizer"`
+	TokenCount int       `json:"tokens"`
+	Content    string    `json:"content"`
+	Binary     bool      `json:"binary,omitempty"`
+}
+
+// CacheStats describes the contents of the cache directory
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Bytes   int64
+}
+
+// Cache stores processed file contents on disk so unchanged files are not
+// read and tokenized again. Every entry lives in its own file and is
+// written atomically, which makes the cache safe to share between
+// concurrent code2txt processes. Entries hold file contents, so they are
+// only readable by the user.
+type Cache struct {
+	dir string
+	// secured makes sure entries directories created by older versions
+	// are private too
+	secured sync.Once
+}
+
+// DefaultCacheDir returns the cache directory inside the user cache dir,
+// or $CODE2TXT_CACHE_DIR when set
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("CODE2TXT_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "code2txt"), nil
+}
+
+// NewCache creates a cache rooted at dir. The directory is created on the
+// first write.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) entriesDir() string {
+	return filepath.Join(c.dir, "entries")
+}
+
+// entryPath maps a file path to its entry file, spreading entries over
+// subdirectories to keep directory sizes small
+func (c *Cache) entryPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(path))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.entriesDir(), key[:2], key+".json")
+}
+
+// Get returns the entry stored for path. Missing or unreadable entries are
+// reported as a miss.
+func (c *Cache) Get(path string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(c.entryPath(path))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Put stores entry, replacing any previous entry for the same path
+func (c *Cache) Put(entry *CacheEntry) error {
+	path := c.entryPath(entry.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	c.secured.Do(func() {
+		os.Chmod(c.entriesDir(), 0700)
+	})
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, 0600)
+}
+
+// Stats counts the entries in the cache and their size on disk
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.dir}
+
+	err := filepath.WalkDir(c.entriesDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			// Entry was replaced or cleared by another process
+			return nil
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+		return nil
+	})
+
+	return stats, err
+}
+
+// Clear removes every entry from the cache
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.entriesDir())
+}
+
+// HashContent returns the hex SHA-256 of content
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File classes assigned by ClassifyFile
+const (
+	ClassSource    = "source"
+	ClassTest      = "test"
+	ClassGenerated = "generated"
+	ClassVendored  = "vendored"
+	ClassLockfile  = "lockfile"
+	ClassMinified  = "minified"
+	ClassDocs      = "docs"
+)
+
+// FileClasses lists every class ClassifyFile can return
+func FileClasses() []string {
+	return []string{ClassSource, ClassTest, ClassGenerated, ClassVendored, ClassLockfile, ClassMinified, ClassDocs}
+}
+
+// vendorDirs hold third-party code copied into the repository
+var vendorDirs = []string{"vendor", "third_party", "third-party", "node_modules", "bower_components", "Pods"}
+
+var lockfileNames = []string{
+	"go.sum", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "npm-shrinkwrap.json",
+	"bun.lockb", "Cargo.lock", "Gemfile.lock", "poetry.lock", "Pipfile.lock", "uv.lock",
+	"composer.lock", "Podfile.lock", "mix.lock", "pubspec.lock", "flake.lock", "packages.lock.json",
+}
+
+// generatedPatterns match the names of files produced by code generators
+var generatedPatterns = []string{
+	"*.pb.go", "*.pb.gw.go", "*_pb2.py", "*_pb2_grpc.py", "*.pb.h", "*.pb.cc",
+	"zz_generated*", "*_generated.go", "*.gen.go", "*.generated.*", "*_gen.go",
+	"*.g.dart", "*.freezed.dart", "*.designer.cs",
+}
+
+var minifiedPatterns = []string{"*.min.js", "*.min.css", "*.min.mjs", "*-min.js", "*.bundle.js"}
+
+var testPatterns = []string{
+	"*_test.go", "test_*.py", "*_test.py", "*_spec.rb", "*_test.rb",
+	"*.test.js", "*.test.ts", "*.test.jsx", "*.test.tsx", "*.spec.js", "*.spec.ts", "*.spec.jsx", "*.spec.tsx",
+	"*Test.java", "*Tests.java", "*Test.kt", "*Tests.cs", "*_test.rs", "*_test.exs", "*_test.dart",
+}
+
+var testDirs = []string{"test", "tests", "__tests__", "spec", "testdata"}
+
+var docsExtensions = []string{".md", ".markdown", ".rst", ".adoc", ".txt"}
+
+var docsDirs = []string{"docs", "doc", "documentation"}
+
+// goGeneratedHeader is the marker of generated Go files, which must come
+// before the package clause: https://go.dev/s/generatedcode
+var goGeneratedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedHeader matches the markers other generators put in the comment
+// block at the top of a file
+var generatedHeader = regexp.MustCompile(`(?:Code generated .* DO NOT EDIT\.|@generated\b|This file was automatically generated|Autogenerated by|AUTO-GENERATED FILE)`)
+
+// commentPrefixes start the lines of a leading comment block
+var commentPrefixes = []string{"//", "#", "/*", "*", "--", ";", "<!--", "{-", "(*", "%"}
+
+// classifyHeadSize is how much of the content ClassifyFile looks at, so
+// that callers that do not read whole files can pass just that much
+const classifyHeadSize = 8192
+
+// ClassifyFile returns the class of the file at relPath with the given
+// content. Checks go from the most to the least specific, so a generated
+// test file is classified as generated.
+func ClassifyFile(relPath, content string) string {
+	if len(content) > classifyHeadSize {
+		content = content[:classifyHeadSize]
+	}
+	slashPath := filepath.ToSlash(relPath)
+	name := path.Base(slashPath)
+	dirs := strings.Split(path.Dir(slashPath), "/")
+
+	switch {
+	case hasAnyDir(dirs, vendorDirs):
+		return ClassVendored
+	case matchesAny(name, lockfileNames):
+		return ClassLockfile
+	case matchesAny(name, generatedPatterns) || hasGeneratedHeader(name, content):
+		return ClassGenerated
+	case matchesAny(name, minifiedPatterns) || looksMinified(content):
+		return ClassMinified
+	case matchesAny(name, testPatterns) || hasAnyDir(dirs, testDirs):
+		return ClassTest
+	case hasAnyExt(name, docsExtensions) || hasAnyDir(dirs, docsDirs):
+		return ClassDocs
+	default:
+		return ClassSource
+	}
+}
+
+// hasGeneratedHeader looks for a generated-code marker in the comments at
+// the top of the file, where generators put it. Go files follow the Go rule:
+// the marker is a line of its own before the package clause.
+func hasGeneratedHeader(name, content string) bool {
+	var blockEnd string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if path.Ext(name) == ".go" {
+			if strings.HasPrefix(line, "package ") {
+				return false
+			}
+			if goGeneratedHeader.MatchString(line) {
+				return true
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case blockEnd != "":
+			// Inside a block comment, whatever the line starts with
+			if strings.Contains(trimmed, blockEnd) {
+				blockEnd = ""
+			}
+		case trimmed == "":
+			continue
+		case !hasAnyPrefix(trimmed, commentPrefixes):
+			return false
+		case strings.HasPrefix(trimmed, "/*") && !strings.Contains(trimmed[2:], "*/"):
+			blockEnd = "*/"
+		case strings.HasPrefix(trimmed, "<!--") && !strings.Contains(trimmed, "-->"):
+			blockEnd = "-->"
+		}
+		if generatedHeader.MatchString(trimmed) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksMinified reports whether content is made of very long lines, as
+// produced by minifiers and bundlers
+func looksMinified(content string) bool {
+	const minSize = 2048
+	if len(content) < minSize {
+		return false
+	}
+	lines := strings.Count(content, "\n") + 1
+	return len(content)/lines > 500
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyDir(dirs, names []string) bool {
+	for _, dir := range dirs {
+		for _, name := range names {
+			if dir == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAnyExt(name string, exts []string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestClassifyFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		expected string
+	}{
+		{"main.go", "package main\n", ClassSource},
+		{"internal/scanner_test.go", "package internal\n", ClassTest},
+		{"src/app.spec.ts", "describe()\n", ClassTest},
+		{"tests/helpers.py", "import os\n", ClassTest},
+		{"api/v1/api.pb.go", "package v1\n", ClassGenerated},
+		{"pkg/zz_generated.deepcopy.go", "package pkg\n", ClassGenerated},
+		{"pkg/mock.go", "// Code generated by MockGen. DO NOT EDIT.\npackage pkg\n", ClassGenerated},
+		{"pkg/mock_test.go", "// Code generated by MockGen. DO NOT EDIT.\npackage pkg\n", ClassGenerated},
+		{"pkg/parser.go", "// Copyright 2024\n\n// Code generated by goyacc. DO NOT EDIT.\n\npackage pkg\n", ClassGenerated},
+		{"pkg/gen.go", "package pkg\n\n// Code generated by stringer. DO NOT EDIT.\n", ClassSource},
+		{"pkg/doc.go", "//Code generated by hand. DO NOT EDIT.\npackage pkg\n", ClassSource},
+		{"web/api.ts", "/*\n * This file was automatically generated.\n */\nexport {}\n", ClassGenerated},
+		{"web/schema.py", "#!/usr/bin/env python\n# @generated by protoc\nimport os\n", ClassGenerated},
+		{"web/check.py", "import os\n\n# @generated markers are found by this tool\n", ClassSource},
+		{"vendor/github.com/x/y/y.go", "package y\n", ClassVendored},
+		{"go.sum", "", ClassLockfile},
+		{"web/package-lock.json", "{}", ClassLockfile},
+		{"static/app.min.js", "x", ClassMinified},
+		{"static/bundle.js", strings.Repeat("var a=1;", 400), ClassMinified},
+		{"README.md", "# Project\n", ClassDocs},
+		{"docs/design.go", "package docs\n", ClassDocs},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyFile(filepath.FromSlash(tt.path), tt.content); got != tt.expected {
+			t.Errorf("ClassifyFile(%s) = %s, expected %s", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestScanClassFilters(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":      {Data: []byte("package main\n")},
+		"main_test.go": {Data: []byte("package main\n")},
+		"api.pb.go":    {Data: []byte("package main\n")},
+	}
+
+	scan := func(options *ScanOptions) []string {
+		result, err := NewScanner(options).ScanFS(context.Background(), fsys, "project")
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		var names []string
+		for _, file := range result.Files {
+			if !file.IsDirectory {
+				names = append(names, file.RelativePath+":"+file.Class)
+			}
+		}
+		return names
+	}
+
+	if got := strings.Join(scan(&ScanOptions{}), " "); got != "api.pb.go:generated main.go:source main_test.go:test" {
+		t.Errorf("Unexpected classes: %s", got)
+	}
+	if got := strings.Join(scan(&ScanOptions{SkipClasses: []string{ClassTest, ClassGenerated}}), " "); got != "main.go:source" {
+		t.Errorf("Expected only main.go, got %s", got)
+	}
+	if got := strings.Join(scan(&ScanOptions{OnlyClasses: []string{ClassTest}}), " "); got != "main_test.go:test" {
+		t.Errorf("Expected only main_test.go, got %s", got)
+	}
+}
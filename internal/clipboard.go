@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Clipboard is a destination that formatted output can be copied to
+type Clipboard interface {
+	// Name identifies the backend in status messages
+	Name() string
+	// Copy replaces the clipboard contents with text
+	Copy(text string) error
+}
+
+// CommandClipboard copies text by piping it into an external helper
+// such as wl-copy, xclip or pbcopy
+type CommandClipboard struct {
+	Command string
+	Args    []string
+}
+
+func (c *CommandClipboard) Name() string {
+	return c.Command
+}
+
+func (c *CommandClipboard) Copy(text string) error {
+	cmd := exec.Command(c.Command, c.Args...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg != "" {
+			return fmt.Errorf("%s: %w: %s", c.Command, err, msg)
+		}
+		return fmt.Errorf("%s: %w", c.Command, err)
+	}
+	return nil
+}
+
+// OSC52Clipboard copies text by writing an OSC 52 escape sequence to the
+// terminal. The terminal emulator sets its own clipboard, which makes this
+// work over SSH where no local clipboard helper is reachable.
+type OSC52Clipboard struct {
+	Writer io.Writer
+	// Tmux wraps the sequence in a tmux passthrough so it reaches the
+	// outer terminal
+	Tmux bool
+}
+
+func (c *OSC52Clipboard) Name() string {
+	return "osc52"
+}
+
+func (c *OSC52Clipboard) Copy(text string) error {
+	_, err := io.WriteString(c.Writer, OSC52Sequence(text, c.Tmux))
+	return err
+}
+
+// OSC52Sequence returns the escape sequence that sets the system clipboard
+// to text
+func OSC52Sequence(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		// Inside tmux every ESC of the payload has to be doubled
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// ClipboardEnv describes the environment used to pick a clipboard backend
+type ClipboardEnv struct {
+	Getenv   func(string) string
+	LookPath func(string) (string, error)
+	GOOS     string
+	// Terminal receives OSC 52 sequences
+	Terminal io.Writer
+}
+
+// clipboardHelpers maps backend names to their external helpers. The order
+// they are tried in is up to detectClipboard.
+var clipboardHelpers = map[string]*CommandClipboard{
+	"wl-copy": {Command: "wl-copy"},
+	"xclip":   {Command: "xclip", Args: []string{"-selection", "clipboard"}},
+	"xsel":    {Command: "xsel", Args: []string{"--clipboard", "--input"}},
+	"pbcopy":  {Command: "pbcopy"},
+	"clip":    {Command: "clip.exe"},
+}
+
+// ClipboardBackends returns the names accepted by NewClipboard
+func ClipboardBackends() []string {
+	return []string{"auto", "wl-copy", "xclip", "xsel", "pbcopy", "clip", "osc52"}
+}
+
+// NewClipboard returns the clipboard backend with the given name. The name
+// "auto" (or an empty name) detects the best backend for the environment.
+func NewClipboard(name string, env *ClipboardEnv) (Clipboard, error) {
+	if env == nil {
+		env = &ClipboardEnv{}
+	}
+	if env.GOOS == "" {
+		env.GOOS = runtime.GOOS
+	}
+	if env.LookPath == nil {
+		env.LookPath = exec.LookPath
+	}
+	if env.Getenv == nil {
+		env.Getenv = func(string) string { return "" }
+	}
+
+	switch name {
+	case "", "auto":
+		return detectClipboard(env)
+	case "osc52":
+		return newOSC52Clipboard(env)
+	}
+
+	helper, ok := clipboardHelpers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown clipboard backend %q (available: %s)",
+			name, strings.Join(ClipboardBackends(), ", "))
+	}
+	if _, err := env.LookPath(helper.Command); err != nil {
+		return nil, fmt.Errorf("clipboard helper %s not found in PATH", helper.Command)
+	}
+	return helper, nil
+}
+
+func detectClipboard(env *ClipboardEnv) (Clipboard, error) {
+	// Over SSH the local helpers would copy to the remote machine's
+	// clipboard, so let the terminal handle it
+	if env.Getenv("SSH_TTY") != "" || env.Getenv("SSH_CONNECTION") != "" {
+		return newOSC52Clipboard(env)
+	}
+
+	var candidates []string
+	switch env.GOOS {
+	case "darwin":
+		candidates = []string{"pbcopy"}
+	case "windows":
+		candidates = []string{"clip"}
+	default:
+		if env.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, "wl-copy")
+		}
+		if env.Getenv("DISPLAY") != "" {
+			candidates = append(candidates, "xclip", "xsel")
+		}
+	}
+
+	for _, name := range candidates {
+		helper := clipboardHelpers[name]
+		if _, err := env.LookPath(helper.Command); err == nil {
+			return helper, nil
+		}
+	}
+
+	// No helper available, fall back to the terminal
+	return newOSC52Clipboard(env)
+}
+
+func newOSC52Clipboard(env *ClipboardEnv) (Clipboard, error) {
+	if env.Terminal == nil {
+		return nil, fmt.Errorf("no clipboard helper found and no terminal available for OSC 52")
+	}
+	return &OSC52Clipboard{
+		Writer: env.Terminal,
+		Tmux:   env.Getenv("TMUX") != "",
+	}, nil
+}
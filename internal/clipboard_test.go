@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func fakeEnv(vars map[string]string, installed ...string) *ClipboardEnv {
+	return &ClipboardEnv{
+		Getenv: func(key string) string { return vars[key] },
+		LookPath: func(name string) (string, error) {
+			for _, bin := range installed {
+				if bin == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+		GOOS:     "linux",
+		Terminal: &bytes.Buffer{},
+	}
+}
+
+func TestOSC52Clipboard(t *testing.T) {
+	var terminal bytes.Buffer
+	clipboard := &OSC52Clipboard{Writer: &terminal}
+
+	if err := clipboard.Copy("hello\nworld"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	expected := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("hello\nworld")) + "\a"
+	if terminal.String() != expected {
+		t.Errorf("Expected sequence %q, got %q", expected, terminal.String())
+	}
+}
+
+func TestOSC52SequenceTmux(t *testing.T) {
+	seq := OSC52Sequence("hi", true)
+
+	if !strings.HasPrefix(seq, "\x1bPtmux;\x1b\x1b]52;c;") {
+		t.Errorf("Expected tmux passthrough prefix, got %q", seq)
+	}
+	if !strings.HasSuffix(seq, "\a\x1b\\") {
+		t.Errorf("Expected tmux passthrough suffix, got %q", seq)
+	}
+}
+
+func TestDetectClipboard(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      map[string]string
+		installed []string
+		expected  string
+	}{
+		{"Wayland", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, "wl-copy"},
+		{"X11 xclip", map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}, "xclip"},
+		{"X11 xsel", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, "xsel"},
+		{"SSH session", map[string]string{"SSH_TTY": "/dev/pts/0", "DISPLAY": ":0"}, []string{"xclip"}, "osc52"},
+		{"No helper", map[string]string{"DISPLAY": ":0"}, nil, "osc52"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clipboard, err := NewClipboard("auto", fakeEnv(test.vars, test.installed...))
+			if err != nil {
+				t.Fatalf("NewClipboard failed: %v", err)
+			}
+			if clipboard.Name() != test.expected {
+				t.Errorf("Expected backend %s, got %s", test.expected, clipboard.Name())
+			}
+		})
+	}
+}
+
+func TestNewClipboardByName(t *testing.T) {
+	if _, err := NewClipboard("xclip", fakeEnv(nil)); err == nil {
+		t.Error("Expected error for missing helper, got nil")
+	}
+
+	if _, err := NewClipboard("bogus", fakeEnv(nil)); err == nil {
+		t.Error("Expected error for unknown backend, got nil")
+	}
+
+	clipboard, err := NewClipboard("osc52", fakeEnv(map[string]string{"TMUX": "/tmp/tmux"}))
+	if err != nil {
+		t.Fatalf("NewClipboard failed: %v", err)
+	}
+	if !clipboard.(*OSC52Clipboard).Tmux {
+		t.Error("Expected tmux passthrough inside tmux")
+	}
+}
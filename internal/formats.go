@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// markdownFence returns a code fence longer than any backtick run in
+// content, so the content cannot close the block early
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// fenceLanguage returns the info string used to highlight a code block,
+// falling back to the extension for unknown languages
+func fenceLanguage(file *FileInfo) string {
+	return FenceLanguage(file.Language, file.RelativePath)
+}
+
+// FenceLanguage returns the code fence language of a file detected as
+// language, falling back to the extension of path
+func FenceLanguage(language, path string) string {
+	if lang, ok := LookupLanguage(language); ok {
+		return lang.Fence
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// jsonOutput is the document written by the json format. Its fields use the
+// same names as ScanResult so a dump can be decoded back into one.
+type jsonOutput struct {
+	RootPath    string      `json:"root"`
+	TotalFiles  int         `json:"totalFiles"`
+	TotalTokens int         `json:"totalTokens"`
+	Incomplete  bool        `json:"incomplete,omitempty"`
+	Roots       []string    `json:"roots,omitempty"`
+	Part        int         `json:"part,omitempty"`
+	Parts       int         `json:"parts,omitempty"`
+	Prompt      *Prompt     `json:"prompt,omitempty"`
+	Tree        *TreeNode   `json:"tree,omitempty"`
+	Files       []*FileInfo `json:"files"`
+}
+
+func (f *OutputFormatter) writeJSON(output *errWriter, result *ScanResult) {
+	doc := jsonOutput{
+		RootPath:    filepath.Base(result.RootPath),
+		TotalFiles:  result.TotalFiles,
+		TotalTokens: result.TotalTokens + f.prompt(result).Tokens(),
+		Incomplete:  result.Incomplete,
+		Roots:       result.Roots,
+		Part:        result.Part,
+		Parts:       result.Parts,
+		Prompt:      f.prompt(result),
+		Files:       contentFiles(result),
+	}
+	if f.options.ShowTree {
+		doc.Tree = BuildTree(result)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		output.err = err
+		return
+	}
+	output.WriteString(string(data) + "\n")
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func formatTestResult() *ScanResult {
+	return &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Content: "package main\n", TokenCount: 3},
+			{RelativePath: "README.md", Content: "Use ```go``` and <b>tags</b> & more", TokenCount: 9},
+		},
+		TotalFiles:  2,
+		TotalTokens: 12,
+	}
+}
+
+func TestMarkdownFormat(t *testing.T) {
+	formatter := NewOutputFormatter(&OutputOptions{ShowTree: true, Format: "markdown"})
+	output := formatter.FormatOutput(formatTestResult())
+
+	if !strings.Contains(output, "### main.go\n\n```go\npackage main\n```\n") {
+		t.Errorf("Expected fenced Go block, got:\n%s", output)
+	}
+	// Content with a triple backtick gets a longer fence
+	if !strings.Contains(output, "````md\n") {
+		t.Errorf("Expected a four-backtick fence, got:\n%s", output)
+	}
+}
+
+func TestXMLFormat(t *testing.T) {
+	formatter := NewOutputFormatter(&OutputOptions{ShowTokens: true, Format: "xml"})
+	output := formatter.FormatOutput(formatTestResult())
+
+	if !strings.HasPrefix(output, `<repository name="project" files="2" tokens="12">`) {
+		t.Errorf("Unexpected repository element:\n%s", output)
+	}
+	if !strings.Contains(output, "&lt;b&gt;tags&lt;/b&gt; &amp; more") {
+		t.Errorf("Expected escaped content, got:\n%s", output)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	formatter := NewOutputFormatter(&OutputOptions{ShowTree: true, Format: "json"})
+	output := formatter.FormatOutput(formatTestResult())
+
+	var decoded ScanResult
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON output: %v", err)
+	}
+	if decoded.TotalTokens != 12 || len(decoded.Files) != 2 || decoded.Files[0].RelativePath != "README.md" {
+		t.Errorf("Unexpected decoded result %+v", decoded)
+	}
+}
+
+func TestApplyTokenBudget(t *testing.T) {
+	result := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "big", IsDirectory: true},
+			{RelativePath: "big/huge.go", TokenCount: 100},
+			{RelativePath: "a.go", TokenCount: 10},
+			{RelativePath: "c.go", TokenCount: 5},
+		},
+		TotalFiles:  3,
+		TotalTokens: 115,
+	}
+
+	omitted := ApplyTokenBudget(result, 20)
+	if len(omitted) != 1 || omitted[0].RelativePath != "big/huge.go" {
+		t.Fatalf("Expected huge.go to be omitted, got %v", omitted)
+	}
+	if result.TotalTokens != 15 || result.TotalFiles != 2 {
+		t.Errorf("Expected 2 files and 15 tokens, got %d files and %d tokens", result.TotalFiles, result.TotalTokens)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("Expected the empty directory to be dropped, got %d entries", len(result.Files))
+	}
+}
+
+func TestNumberLinesAcrossChunks(t *testing.T) {
+	if got := NumberLines("a\nb\n", 9); got != " 9 | a\n10 | b\n" {
+		t.Errorf("Unexpected numbering from line 9: %q", got)
+	}
+
+	// A 12-line file split after line 7, each part rendered on its own
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line%d\n", i))
+	}
+	first := strings.Join(lines[:7], "")
+	second := strings.Join(lines[7:], "")
+	formatter := NewOutputFormatter(&OutputOptions{LineNumbers: true})
+
+	output := formatter.FormatOutput(&ScanResult{RootPath: "project", Files: []*FileInfo{
+		{RelativePath: "big.txt", Content: first, TokenCount: CountTokens(first)},
+	}})
+	if !strings.Contains(output, "1 | line1\n") || !strings.Contains(output, "7 | line7\n") {
+		t.Errorf("Expected lines 1 to 7 in the first part:\n%s", output)
+	}
+
+	output = formatter.FormatOutput(&ScanResult{RootPath: "project", Files: []*FileInfo{
+		{RelativePath: "big.txt", Content: second, StartLine: 8, TokenCount: CountTokens(second)},
+	}})
+	if !strings.Contains(output, " 8 | line8\n") || !strings.Contains(output, "12 | line12\n") || strings.Contains(output, " 1 | line8") {
+		t.Errorf("Expected numbering to continue from 8 in the second part:\n%s", output)
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	content := strings.Repeat("x\n", 9) + "last"
+	result := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "a.txt", Content: content, TokenCount: CountTokens(content)},
+			// The second part of a file split across chunks
+			{RelativePath: "b.txt", Content: "more\n", StartLine: 99, TokenCount: 1},
+		},
+		TotalFiles:  2,
+		TotalTokens: CountTokens(content) + 1,
+	}
+
+	formatter := NewOutputFormatter(&OutputOptions{ShowTokens: true, LineNumbers: true, Format: "markdown"})
+	output := formatter.FormatOutput(result)
+	for _, want := range []string{" 1 | x\n", " 9 | x\n", "10 | last\n", "99 | more\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+
+	prepared := formatter.Prepare(result)
+	if prepared.Files[0].TokenCount != CountTokens(prepared.Files[0].Content) || prepared.TotalTokens <= result.TotalTokens {
+		t.Errorf("Expected token counts of the numbered content, got %d (was %d)", prepared.TotalTokens, result.TotalTokens)
+	}
+	if result.Files[0].Content != content {
+		t.Errorf("Prepare should not modify the scan result")
+	}
+	// Formatting a prepared result does not number it again
+	if again := formatter.FormatOutput(prepared); again != output {
+		t.Errorf("Expected the same output for a prepared result, got:\n%s", again)
+	}
+}
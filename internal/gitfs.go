@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing/fstest"
+	"time"
+)
+
+// gitBlob is a file listed by git ls-tree
+type gitBlob struct {
+	mode   fs.FileMode
+	object string
+	size   int64
+	path   string
+}
+
+// IsBareGitRepo reports whether dir looks like a bare git repository
+func IsBareGitRepo(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// OpenGitTree returns the files of ref in the git repository at repoPath,
+// which may be bare or have a working tree. Files are read straight from
+// the object store, so nothing is checked out. Every file carries the
+// commit time as its modification time. Cancelling ctx stops git.
+func OpenGitTree(ctx context.Context, repoPath, ref string) (fs.FS, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required to read refs: %w", err)
+	}
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid ref %q", ref)
+	}
+
+	out, err := runGit(ctx, repoPath, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("unknown ref %q in %s", ref, repoPath)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	out, err = runGit(ctx, repoPath, nil, "show", "-s", "--format=%ct", commit)
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected commit time %q", out)
+	}
+	modTime := time.Unix(seconds, 0)
+
+	out, err = runGit(ctx, repoPath, nil, "ls-tree", "-r", "-l", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := parseLsTree(out)
+	if err != nil {
+		return nil, err
+	}
+
+	// The scanner skips large files anyway, so they are only added as stubs
+	fsys := newMemFS()
+	wanted := make([]gitBlob, 0, len(blobs))
+	for _, blob := range blobs {
+		if blob.size > maxFileSize {
+			fsys.addStub(blob.path, blob.size, &fstest.MapFile{Mode: blob.mode, ModTime: modTime})
+			continue
+		}
+		wanted = append(wanted, blob)
+	}
+	if err := readGitBlobs(ctx, repoPath, wanted, modTime, fsys); err != nil {
+		return nil, err
+	}
+	return fsys, nil
+}
+
+// readGitBlobs adds the contents of blobs to fsys. They are streamed from a
+// single git cat-file process, which is killed when ctx is cancelled.
+func readGitBlobs(ctx context.Context, repoPath string, blobs []gitBlob, modTime time.Time, fsys *memFS) error {
+	var request bytes.Buffer
+	for _, blob := range blobs {
+		fmt.Fprintln(&request, blob.object)
+	}
+
+	// Cancelling also stops git when reading fails halfway
+	gitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(gitCtx, "git", "-C", repoPath, "cat-file", "--batch")
+	cmd.Stdin = &request
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git cat-file: %w", err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	for _, blob := range blobs {
+		data, err := readBatchObject(reader)
+		if err != nil {
+			cancel()
+			cmd.Wait()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read %s: %w", blob.path, err)
+		}
+		fsys.MapFS[blob.path] = &fstest.MapFile{Data: data, Mode: blob.mode, ModTime: modTime}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return gitError("cat-file", &stderr, err)
+	}
+	return nil
+}
+
+// parseLsTree parses the output of git ls-tree -r -l -z. Only regular
+// files are returned; symlinks and submodules are skipped.
+func parseLsTree(out []byte) ([]gitBlob, error) {
+	blobs := make([]gitBlob, 0)
+
+	for _, record := range strings.Split(string(out), "\x00") {
+		if record == "" {
+			continue
+		}
+
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		meta, name, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", record)
+		}
+		if fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", record)
+		}
+		if !fs.ValidPath(name) {
+			continue
+		}
+
+		mode := fs.FileMode(0644)
+		if fields[0] == "100755" {
+			mode = 0755
+		}
+		blobs = append(blobs, gitBlob{mode: mode, object: fields[2], size: size, path: name})
+	}
+
+	return blobs, nil
+}
+
+// readBatchObject reads one "<object> <type> <size>" header and its
+// content from git cat-file --batch output
+func readBatchObject(r *bufio.Reader) ([]byte, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected cat-file output %q", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected cat-file output %q", strings.TrimSpace(header))
+	}
+
+	// Content is followed by a newline
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+func runGit(ctx context.Context, repoPath string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, gitError(args[0], &stderr, err)
+	}
+	return out, nil
+}
+
+// gitError describes the failure of a git command, preferring its own
+// message on stderr
+func gitError(command string, stderr *bytes.Buffer, err error) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("git %s: %s", command, msg)
+	}
+	return fmt.Errorf("git %s: %w", command, err)
+}
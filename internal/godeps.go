@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Roles of the files selected by GoClosure
+const (
+	RoleEntry      = "entry"
+	RoleDependency = "dependency"
+	RoleDependent  = "dependent"
+)
+
+// goPackage is a directory of Go files inside a module
+type goPackage struct {
+	files []string
+	// imports holds the directories of the imported packages that belong
+	// to the same module
+	imports map[string]bool
+}
+
+// goModule indexes the packages of a module by directory
+type goModule struct {
+	dir      string
+	path     string
+	packages map[string]*goPackage
+}
+
+// GoClosure returns the entry files plus every Go file they depend on
+// within their module, mapped to their role. Imports are followed
+// transitively; packages outside the module are ignored. With reverse, the
+// packages that directly import an entry's package are included as well.
+// Test files are only included when given as an entry, and files excluded
+// by build constraints for the current platform are left out. Relative
+// entries are resolved against root. Returned paths are absolute.
+func GoClosure(root string, entries []string, reverse bool) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entry files given")
+	}
+
+	var module *goModule
+	absEntries := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(root, entry)
+		}
+		abs, err := filepath.Abs(entry)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(abs); err != nil || info.IsDir() || filepath.Ext(abs) != ".go" {
+			return nil, fmt.Errorf("entry is not a Go file: %s", entry)
+		}
+
+		moduleDir, modulePath, err := findGoModule(filepath.Dir(abs))
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", entry, err)
+		}
+		if module == nil {
+			if module, err = loadGoModule(moduleDir, modulePath); err != nil {
+				return nil, err
+			}
+		} else if module.dir != moduleDir {
+			return nil, fmt.Errorf("entries belong to different modules: %s and %s", module.dir, moduleDir)
+		}
+		absEntries = append(absEntries, abs)
+	}
+
+	roles := make(map[string]string)
+	visited := make(map[string]bool)
+	queue := make([]string, 0)
+	entryPackages := make(map[string]bool)
+
+	for _, entry := range absEntries {
+		dir := filepath.Dir(entry)
+		entryPackages[dir] = true
+		queue = append(queue, dir)
+
+		// A test entry may import packages its package does not
+		imports, err := module.fileImports(entry)
+		if err != nil {
+			return nil, err
+		}
+		for dir := range imports {
+			queue = append(queue, dir)
+		}
+	}
+
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		if visited[dir] {
+			continue
+		}
+		visited[dir] = true
+
+		pkg := module.packages[dir]
+		if pkg == nil {
+			continue
+		}
+		for _, file := range pkg.files {
+			roles[file] = RoleDependency
+		}
+		for imported := range pkg.imports {
+			queue = append(queue, imported)
+		}
+	}
+
+	if reverse {
+		for _, pkg := range module.packages {
+			for imported := range pkg.imports {
+				if !entryPackages[imported] {
+					continue
+				}
+				for _, file := range pkg.files {
+					if _, ok := roles[file]; !ok {
+						roles[file] = RoleDependent
+					}
+				}
+				break
+			}
+		}
+	}
+
+	for _, entry := range absEntries {
+		roles[entry] = RoleEntry
+	}
+
+	return roles, nil
+}
+
+// findGoModule looks for go.mod in dir and its parents and returns the
+// module directory and path
+func findGoModule(dir string) (string, string, error) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			modulePath := parseModulePath(string(data))
+			if modulePath == "" {
+				return "", "", fmt.Errorf("no module directive in %s", filepath.Join(dir, "go.mod"))
+			}
+			return dir, modulePath, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// parseModulePath returns the path from the module directive of a go.mod
+func parseModulePath(gomod string) string {
+	scanner := bufio.NewScanner(strings.NewReader(gomod))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if i := strings.Index(rest, "//"); i >= 0 {
+			rest = strings.TrimSpace(rest[:i])
+		}
+		if unquoted, err := strconv.Unquote(rest); err == nil {
+			rest = unquoted
+		}
+		if rest != "" {
+			return rest
+		}
+	}
+	return ""
+}
+
+// loadGoModule parses the imports of every non-test Go file in the module
+// that builds on the current platform. vendor and testdata directories,
+// hidden directories and nested modules are skipped, as the go tool does.
+func loadGoModule(dir, path string) (*goModule, error) {
+	module := &goModule{dir: dir, path: path, packages: make(map[string]*goPackage)}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		if match, err := build.Default.MatchFile(filepath.Dir(path), name); err != nil || !match {
+			return nil
+		}
+
+		imports, err := module.fileImports(path)
+		if err != nil {
+			// Files that don't parse can't contribute imports
+			return nil
+		}
+
+		pkgDir := filepath.Dir(path)
+		pkg := module.packages[pkgDir]
+		if pkg == nil {
+			pkg = &goPackage{imports: make(map[string]bool)}
+			module.packages[pkgDir] = pkg
+		}
+		pkg.files = append(pkg.files, path)
+		for imported := range imports {
+			pkg.imports[imported] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range module.packages {
+		sort.Strings(pkg.files)
+	}
+	return module, nil
+}
+
+// fileImports returns the directories of the module packages imported by
+// the Go file at path
+func (m *goModule) fileImports(path string) (map[string]bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make(map[string]bool)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if importPath == m.path {
+			imports[m.dir] = true
+		} else if rel, ok := strings.CutPrefix(importPath, m.path+"/"); ok {
+			imports[filepath.Join(m.dir, filepath.FromSlash(rel))] = true
+		}
+	}
+	return imports, nil
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestModule(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":                "module example.com/app // comment\n\ngo 1.21\n",
+		"cmd/app/main.go":       "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/server\"\n)\n\nfunc main() { fmt.Println(server.Name) }\n",
+		"cmd/tool/main.go":      "package main\n\nimport \"example.com/app/store\"\n\nfunc main() { _ = store.Name }\n",
+		"server/server.go":      "package server\n\nimport \"example.com/app/store\"\n\nvar Name = store.Name\n",
+		"server/server_test.go": "package server\n\nimport \"example.com/app/testutil\"\n",
+		"store/store.go":        "package store\n\nvar Name = \"store\"\n",
+		"store/debug.go":        "//go:build debug\n\npackage store\n\nimport \"example.com/app/unused\"\n",
+		"testutil/util.go":      "package testutil\n",
+		"unused/unused.go":      "package unused\n",
+		"vendor/x/x.go":         "package x\n",
+	})
+	return root
+}
+
+// relativeRoles makes the keys of a GoClosure result relative to root
+func relativeRoles(t *testing.T, root string, roles map[string]string) map[string]string {
+	t.Helper()
+	rel := make(map[string]string)
+	for path, role := range roles {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatalf("Unexpected path %s", path)
+		}
+		rel[filepath.ToSlash(relPath)] = role
+	}
+	return rel
+}
+
+func TestGoClosure(t *testing.T) {
+	root := writeTestModule(t)
+
+	roles, err := GoClosure(t.TempDir(), []string{filepath.Join(root, "cmd", "app", "main.go")}, false)
+	if err != nil {
+		t.Fatalf("GoClosure failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"cmd/app/main.go":  RoleEntry,
+		"server/server.go": RoleDependency,
+		"store/store.go":   RoleDependency,
+	}
+	if got := relativeRoles(t, root, roles); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestGoClosureReverse(t *testing.T) {
+	root := writeTestModule(t)
+
+	roles, err := GoClosure(root, []string{filepath.Join("store", "store.go")}, true)
+	if err != nil {
+		t.Fatalf("GoClosure failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"store/store.go":   RoleEntry,
+		"server/server.go": RoleDependent,
+		"cmd/tool/main.go": RoleDependent,
+	}
+	if got := relativeRoles(t, root, roles); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestGoClosureErrors(t *testing.T) {
+	root := writeTestModule(t)
+	outside := t.TempDir()
+	writeTestFiles(t, outside, map[string]string{"main.go": "package main\n"})
+
+	if _, err := GoClosure(root, []string{"store"}, false); err == nil {
+		t.Error("Expected an error for a directory entry")
+	}
+	if _, err := GoClosure(root, []string{filepath.Join(outside, "main.go")}, false); err == nil {
+		t.Error("Expected an error for a file outside any module")
+	}
+}
+
+func TestScanOnlyMarksTree(t *testing.T) {
+	root := writeTestModule(t)
+
+	scanner := NewScanner(&ScanOptions{Only: map[string]string{
+		"cmd/app/main.go": RoleEntry,
+		"store/store.go":  RoleDependency,
+	}})
+	result, err := scanner.ScanDirectoryContext(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if result.TotalFiles != 2 {
+		t.Errorf("Expected 2 files, got %d", result.TotalFiles)
+	}
+
+	tree := RenderTree(BuildTree(result), false)
+	for _, line := range []string{"main.go [entry]", "store.go [dependency]"} {
+		if !strings.Contains(tree, line) {
+			t.Errorf("Expected tree to contain %q, got:\n%s", line, tree)
+		}
+	}
+	// Directories without selected files are not walked at all
+	if strings.Contains(tree, "server") || strings.Contains(tree, "unused") {
+		t.Errorf("Expected unrelated directories to be skipped, got:\n%s", tree)
+	}
+}
@@ -0,0 +1,258 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Language describes a programming or markup language
+type Language struct {
+	Name string `json:"name"`
+	// Fence is the info string used for markdown code blocks
+	Fence        string   `json:"fence"`
+	Extensions   []string `json:"extensions,omitempty"`
+	Filenames    []string `json:"filenames,omitempty"`
+	Interpreters []string `json:"interpreters,omitempty"`
+	Aliases      []string `json:"aliases,omitempty"`
+}
+
+//go:embed languages.json
+var languagesJSON []byte
+
+// languageIndex holds the lookup tables built from languages.json
+type languageIndex struct {
+	languages     []*Language
+	byExtension   map[string]*Language
+	byFilename    map[string]*Language
+	byPattern     []filenamePattern
+	byName        map[string]*Language
+	byInterpreter map[string]*Language
+}
+
+type filenamePattern struct {
+	pattern  string
+	language *Language
+}
+
+var languages = loadLanguages()
+
+func loadLanguages() *languageIndex {
+	var list []*Language
+	if err := json.Unmarshal(languagesJSON, &list); err != nil {
+		panic(fmt.Sprintf("invalid languages.json: %v", err))
+	}
+
+	index := &languageIndex{
+		languages:     list,
+		byExtension:   make(map[string]*Language),
+		byFilename:    make(map[string]*Language),
+		byName:        make(map[string]*Language),
+		byInterpreter: make(map[string]*Language),
+	}
+	for _, lang := range list {
+		index.byName[strings.ToLower(lang.Name)] = lang
+		for _, alias := range lang.Aliases {
+			index.byName[strings.ToLower(alias)] = lang
+		}
+		for _, ext := range lang.Extensions {
+			index.byExtension[strings.ToLower(ext)] = lang
+		}
+		for _, name := range lang.Filenames {
+			if strings.ContainsAny(name, "*?[") {
+				index.byPattern = append(index.byPattern, filenamePattern{name, lang})
+			} else {
+				index.byFilename[name] = lang
+			}
+		}
+		for _, interpreter := range lang.Interpreters {
+			index.byInterpreter[interpreter] = lang
+		}
+	}
+	return index
+}
+
+// Languages returns the known languages sorted by name
+func Languages() []Language {
+	list := make([]Language, 0, len(languages.languages))
+	for _, lang := range languages.languages {
+		list = append(list, *lang)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name)
+	})
+	return list
+}
+
+// LookupLanguage finds a language by name or alias, ignoring case
+func LookupLanguage(name string) (*Language, bool) {
+	lang, ok := languages.byName[strings.ToLower(strings.TrimSpace(name))]
+	return lang, ok
+}
+
+// ValidateLanguages checks that every name is a known language or alias
+func ValidateLanguages(names []string) error {
+	for _, name := range names {
+		if _, ok := LookupLanguage(name); !ok {
+			return fmt.Errorf("unknown language %q (see 'code2txt languages')", name)
+		}
+	}
+	return nil
+}
+
+// DetectLanguage returns the language of the file at relPath, using in
+// order an editor modeline, the file name, the extension and the shebang
+// line. content may be empty to detect by name only. nil means unknown.
+func DetectLanguage(relPath, content string) *Language {
+	if lang := modelineLanguage(content); lang != nil {
+		return lang
+	}
+	if lang := languageByName(relPath); lang != nil {
+		return lang
+	}
+	return shebangLanguage(content)
+}
+
+// languageByName detects the language from the file name and extension
+func languageByName(relPath string) *Language {
+	name := filepath.Base(relPath)
+	if lang, ok := languages.byFilename[name]; ok {
+		return lang
+	}
+	for _, p := range languages.byPattern {
+		if matched, _ := path.Match(p.pattern, name); matched {
+			return p.language
+		}
+	}
+	if lang, ok := languages.byExtension[strings.ToLower(path.Ext(name))]; ok {
+		return lang
+	}
+	return nil
+}
+
+// shebangLanguage detects scripts from their #! line, including the
+// "#!/usr/bin/env python3" form
+func shebangLanguage(content string) *Language {
+	if !strings.HasPrefix(content, "#!") {
+		return nil
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as -S
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+
+	if lang, ok := languages.byInterpreter[interpreter]; ok {
+		return lang
+	}
+	// python3.12 -> python3 -> python
+	trimmed := strings.TrimRight(interpreter, "0123456789.")
+	if lang, ok := languages.byInterpreter[trimmed]; ok {
+		return lang
+	}
+	return nil
+}
+
+var (
+	vimModeline   = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):\s*(?:set?\s+)?.*?\b(?:ft|filetype|syntax)=([\w+#-]+)`)
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*)?([\w+#-]+)\s*(?:;.*)?-\*-`)
+)
+
+// modelineLanguage looks for a vim or emacs modeline in the first and last
+// lines of content
+func modelineLanguage(content string) *Language {
+	if content == "" {
+		return nil
+	}
+
+	const edge = 5
+	lines := strings.SplitN(content, "\n", edge+1)
+	if len(lines) > edge {
+		lines = lines[:edge]
+		if i := nthLastIndex(content, '\n', edge+1); i >= 0 {
+			lines = append(lines, strings.Split(content[i+1:], "\n")...)
+		}
+	}
+
+	for _, line := range lines {
+		for _, re := range []*regexp.Regexp{vimModeline, emacsModeline} {
+			if match := re.FindStringSubmatch(line); match != nil {
+				if lang, ok := LookupLanguage(match[1]); ok {
+					return lang
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nthLastIndex returns the index of the nth last occurrence of c in s
+func nthLastIndex(s string, c byte, n int) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == c {
+			n--
+			if n == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// LanguageStat counts the files and tokens of one language
+type LanguageStat struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Tokens   int    `json:"tokens"`
+}
+
+// LanguageStats breaks the files of result down by language, most tokens
+// first. Files of unknown language are counted as "Other".
+func LanguageStats(result *ScanResult) []LanguageStat {
+	byLanguage := make(map[string]*LanguageStat)
+	for _, file := range result.Files {
+		if file.IsDirectory {
+			continue
+		}
+		name := file.Language
+		if name == "" {
+			name = "Other"
+		}
+		stat := byLanguage[name]
+		if stat == nil {
+			stat = &LanguageStat{Language: name}
+			byLanguage[name] = stat
+		}
+		stat.Files++
+		stat.Tokens += file.TokenCount
+	}
+
+	stats := make([]LanguageStat, 0, len(byLanguage))
+	for _, stat := range byLanguage {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Tokens != stats[j].Tokens {
+			return stats[i].Tokens > stats[j].Tokens
+		}
+		return stats[i].Language < stats[j].Language
+	})
+	return stats
+}
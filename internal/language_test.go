@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		expected string
+	}{
+		{"main.go", "", "Go"},
+		{"web/App.tsx", "", "TSX"},
+		{"web/App.vue", "", "Vue"},
+		{"api/service.proto", "", "Protocol Buffers"},
+		{"infra/main.tf", "", "HCL"},
+		{"Dockerfile", "", "Dockerfile"},
+		{"Dockerfile.dev", "", "Dockerfile"},
+		{"Makefile", "", "Makefile"},
+		{"CMakeLists.txt", "", "CMake"},
+		{"SRC/MAIN.GO", "", "Go"},
+		{"bin/deploy", "#!/bin/bash\necho hi\n", "Shell"},
+		{"bin/tool", "#!/usr/bin/env python3\nprint()\n", "Python"},
+		{"bin/tool", "#!/usr/bin/env -S python3.12 -u\nprint()\n", "Python"},
+		{"scripts/build", "#!/usr/bin/env node\n", "JavaScript"},
+		{"config/rules", "# vim: set ft=yaml:\nkey: value\n", "YAML"},
+		{"notes", "index: see the filetype=ruby section\n", ""},
+		{"notes", "Apex: set ft=ruby for scripts\n", ""},
+		{"lib/thing.inc", "/* -*- mode: c++ -*- */\nint x;\n", "C++"},
+		{"notes", "just some words\n", ""},
+		{"image.png", "", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if lang := DetectLanguage(tt.path, tt.content); lang != nil {
+			got = lang.Name
+		}
+		if got != tt.expected {
+			t.Errorf("DetectLanguage(%s) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestModelineAtEndOfFile(t *testing.T) {
+	content := strings.Repeat("line\n", 20) + "# vim: ft=ruby\n"
+	if lang := DetectLanguage("script", content); lang == nil || lang.Name != "Ruby" {
+		t.Errorf("Expected a trailing modeline to be found, got %v", lang)
+	}
+}
+
+func TestLookupLanguage(t *testing.T) {
+	for _, name := range []string{"go", "Golang", "proto", "terraform", "TS"} {
+		if _, ok := LookupLanguage(name); !ok {
+			t.Errorf("Expected %q to be a known language", name)
+		}
+	}
+	if err := ValidateLanguages([]string{"go", "klingon"}); err == nil {
+		t.Error("Expected an error for an unknown language")
+	}
+}
+
+func TestScanLanguageFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":           {Data: []byte("package main\n")},
+		"api/service.proto": {Data: []byte("syntax = \"proto3\";\n")},
+		"web/app.ts":        {Data: []byte("export {}\n")},
+		"bin/run":           {Data: []byte("#!/bin/sh\necho run\n")},
+	}
+
+	result, err := NewScanner(&ScanOptions{Languages: []string{"go", "proto", "sh"}}).
+		ScanFS(context.Background(), fsys, "project")
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	languages := make(map[string]string)
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			languages[file.RelativePath] = file.Language
+		}
+	}
+	if len(languages) != 3 || languages["main.go"] != "Go" || languages["bin/run"] != "Shell" {
+		t.Errorf("Expected Go, Protocol Buffers and Shell files, got %v", languages)
+	}
+
+	stats := LanguageStats(result)
+	if len(stats) != 3 {
+		t.Errorf("Expected 3 languages in the stats, got %v", stats)
+	}
+
+	output := NewOutputFormatter(&OutputOptions{Format: "markdown"}).FormatOutput(result)
+	if !strings.Contains(output, "```protobuf\n") || !strings.Contains(output, "```bash\n") {
+		t.Errorf("Expected fences from the language table, got:\n%s", output)
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberLines prefixes every line of content with its right-aligned line
+// number, counting from start
+func NumberLines(content string, start int) string {
+	if content == "" {
+		return ""
+	}
+	if start < 1 {
+		start = 1
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	width := len(strconv.Itoa(start + len(lines) - 1))
+
+	var output strings.Builder
+	output.Grow(len(content) + len(lines)*(width+3))
+	for i, line := range lines {
+		fmt.Fprintf(&output, "%*d | %s", width, start+i, line)
+	}
+	return output.String()
+}
+
+// numberedResult returns a copy of result whose files hold line-numbered
+// content, with token counts computed on that content
+func numberedResult(result *ScanResult) *ScanResult {
+	if result.numbered {
+		return result
+	}
+
+	numbered := *result
+	numbered.Files = make([]*FileInfo, len(result.Files))
+	numbered.TotalTokens = 0
+	numbered.numbered = true
+	for i, file := range result.Files {
+		if file.IsDirectory {
+			numbered.Files[i] = file
+			continue
+		}
+		copied := *file
+		copied.Content = NumberLines(file.Content, file.StartLine)
+		copied.TokenCount = CountTokens(copied.Content)
+		copied.original = file
+		numbered.Files[i] = &copied
+		numbered.TotalTokens += copied.TokenCount
+	}
+	return &numbered
+}
+
+// sourceContent returns the content of file as it was scanned, without
+// line numbers
+func sourceContent(file *FileInfo) string {
+	if file.original != nil {
+		return file.original.Content
+	}
+	return file.Content
+}
+
+// sourceFile returns file as it was scanned, without line numbers
+func sourceFile(file *FileInfo) *FileInfo {
+	if file.original != nil {
+		return file.original
+	}
+	return file
+}
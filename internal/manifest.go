@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest records what a dump contains and where it came from, so the
+// exact input can be checked later. It holds no timestamps: the same tree
+// and options always produce the same manifest.
+type Manifest struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version,omitempty"`
+	Root    string `json:"root"`
+	// Git is the state of the scanned repository, nil outside git
+	Git *GitState `json:"git,omitempty"`
+	// Options are the settings the dump was made with
+	Options map[string]string `json:"options,omitempty"`
+	// Redacted is set when secrets were masked, file hashes are then of
+	// the redacted content
+	Redacted     bool           `json:"redacted,omitempty"`
+	OutputSHA256 string         `json:"outputSha256"`
+	OutputSize   int            `json:"outputSize"`
+	TotalFiles   int            `json:"totalFiles"`
+	TotalTokens  int            `json:"totalTokens"`
+	Files        []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one file of a dump
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Tokens int    `json:"tokens"`
+}
+
+// GitState is the commit a scanned tree was at
+type GitState struct {
+	Commit string `json:"commit"`
+	// Dirty is set when the working tree had uncommitted changes
+	Dirty bool `json:"dirty,omitempty"`
+}
+
+// NewManifest describes output, rendered from result. Token counts are the
+// ones of the output, hashes are of the file content before line numbers
+// were added.
+func NewManifest(result *ScanResult, output []byte) *Manifest {
+	manifest := &Manifest{
+		Tool:         "code2txt",
+		Root:         filepath.Base(result.RootPath),
+		OutputSHA256: HashContent(output),
+		OutputSize:   len(output),
+		TotalFiles:   result.TotalFiles,
+		TotalTokens:  result.TotalTokens,
+		Files:        make([]ManifestFile, 0, result.TotalFiles),
+	}
+	for _, file := range contentFiles(result) {
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:   filepath.ToSlash(file.RelativePath),
+			SHA256: HashContent([]byte(sourceContent(file))),
+			Size:   file.Size,
+			Tokens: file.TokenCount,
+		})
+	}
+	return manifest
+}
+
+// ReadManifest loads a manifest written by WriteManifest
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// WriteManifest writes manifest to path as indented JSON
+func WriteManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// ReadGitState returns the commit of the repository at path, at ref when
+// set, or nil when path is not in a git repository. A ref is never dirty.
+func ReadGitState(path, ref string) *GitState {
+	if ref == "" && IsBareGitRepo(path) {
+		ref = "HEAD"
+	}
+	if ref != "" {
+		out, err := runGit(context.Background(), path, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+		if err != nil {
+			return nil
+		}
+		return &GitState{Commit: strings.TrimSpace(string(out))}
+	}
+
+	out, err := runGit(context.Background(), path, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return nil
+	}
+	state := &GitState{Commit: strings.TrimSpace(string(out))}
+	if status, err := runGit(context.Background(), path, nil, "status", "--porcelain", "--", "."); err == nil {
+		state.Dirty = len(status) > 0
+	}
+	return state
+}
+
+// VerifyManifest compares the files listed in manifest with those in
+// fsys and returns one line per file that changed or is missing
+func VerifyManifest(manifest *Manifest, fsys fs.FS) []string {
+	problems := make([]string, 0)
+	for _, file := range manifest.Files {
+		content, err := fs.ReadFile(fsys, file.Path)
+		if err != nil {
+			problems = append(problems, "missing: "+file.Path)
+			continue
+		}
+		if manifest.Redacted {
+			content = []byte(redactedContent(string(content)))
+		}
+		if HashContent(content) != file.SHA256 {
+			problems = append(problems, "modified: "+file.Path)
+		}
+	}
+	return problems
+}
+
+func redactedContent(content string) string {
+	redacted, _ := RedactSecrets(content)
+	return redacted
+}
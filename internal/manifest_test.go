@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestManifest(t *testing.T) {
+	result := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Content: "package main\n", Size: 13, TokenCount: 3},
+			{RelativePath: "lib", IsDirectory: true},
+			{RelativePath: filepath.Join("lib", "lib.go"), Content: "package lib\n", Size: 12, TokenCount: 3},
+		},
+		TotalFiles:  2,
+		TotalTokens: 6,
+	}
+	formatter := NewOutputFormatter(&OutputOptions{LineNumbers: true})
+	prepared := formatter.Prepare(result)
+	output := formatter.FormatOutput(prepared)
+
+	manifest := NewManifest(prepared, []byte(output))
+	if manifest.OutputSHA256 != HashContent([]byte(output)) || len(manifest.Files) != 2 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	if manifest.Files[0].Path != "lib/lib.go" || manifest.Files[1].SHA256 != HashContent([]byte("package main\n")) {
+		t.Errorf("Expected sorted paths and hashes of the content without line numbers, got %+v", manifest.Files)
+	}
+
+	path := filepath.Join(t.TempDir(), "dump.manifest.json")
+	if err := WriteManifest(path, manifest); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(path)
+	if err != nil || read.OutputSHA256 != manifest.OutputSHA256 {
+		t.Fatalf("Failed to read the manifest back: %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"main.go":    {Data: []byte("package main\n")},
+		"lib/lib.go": {Data: []byte("package lib\n")},
+	}
+	if problems := VerifyManifest(read, fsys); len(problems) != 0 {
+		t.Errorf("Expected the tree to match, got %v", problems)
+	}
+
+	fsys["main.go"] = &fstest.MapFile{Data: []byte("package main // changed\n")}
+	delete(fsys, "lib/lib.go")
+	problems := VerifyManifest(read, fsys)
+	if len(problems) != 2 || problems[0] != "missing: lib/lib.go" || problems[1] != "modified: main.go" {
+		t.Errorf("Expected a missing and a modified file, got %v", problems)
+	}
+}
+
+func TestOutputIgnoresWalkOrder(t *testing.T) {
+	files := func() []*FileInfo {
+		return []*FileInfo{
+			{RelativePath: "b", IsDirectory: true},
+			{RelativePath: filepath.Join("b", "x.go"), Content: "x\n", TokenCount: 1},
+			{RelativePath: "a.go", Content: "a\n", TokenCount: 1},
+			{RelativePath: "b.go", Content: "b\n", TokenCount: 1},
+		}
+	}
+	forward := &ScanResult{RootPath: "project", Files: files(), TotalFiles: 3, TotalTokens: 3}
+	reversed := &ScanResult{RootPath: "project", Files: files(), TotalFiles: 3, TotalTokens: 3}
+	for i, j := 0, len(reversed.Files)-1; i < j; i, j = i+1, j-1 {
+		reversed.Files[i], reversed.Files[j] = reversed.Files[j], reversed.Files[i]
+	}
+
+	for _, format := range OutputFormats() {
+		formatter := NewOutputFormatter(&OutputOptions{ShowTokens: true, ShowTree: true, Format: format})
+		if formatter.FormatOutput(forward) != formatter.FormatOutput(reversed) {
+			t.Errorf("Expected the same %s output for any file order", format)
+		}
+	}
+	formatter := NewOutputFormatter(nil)
+	if formatter.FormatFileList(forward) != formatter.FormatFileList(reversed) {
+		t.Error("Expected the same file list for any file order")
+	}
+}
+
+func TestOutputIgnoresModTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := make([]string, 2)
+	for i, modTime := range []time.Time{time.Unix(1000000000, 0), time.Unix(1700000000, 0)} {
+		os.Chtimes(path, modTime, modTime)
+		result, err := NewScanner(&ScanOptions{}).ScanDirectory(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[i] = NewOutputFormatter(&OutputOptions{ShowTree: true, Format: "json"}).FormatOutput(result)
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("Expected the same JSON output after touching a file, got:\n%s\n%s", outputs[0], outputs[1])
+	}
+}
@@ -0,0 +1,507 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MCPProtocolVersion is the Model Context Protocol revision implemented by
+// MCPServer
+const MCPProtocolVersion = "2024-11-05"
+
+// mcpProtocolVersions lists the revisions MCPServer can speak, newest first
+var mcpProtocolVersions = []string{MCPProtocolVersion}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// MCPServer answers Model Context Protocol requests about a single root
+// directory over newline-delimited JSON-RPC. Every tool goes through the
+// Scanner, so the same exclude, .gitignore and redaction rules apply as for
+// a normal scan.
+type MCPServer struct {
+	root    string
+	options ScanOptions
+	version string
+}
+
+// NewMCPServer creates a server for root. options configures every scan;
+// symlinks are never followed.
+func NewMCPServer(root string, options *ScanOptions, version string) *MCPServer {
+	s := &MCPServer{root: root, version: version}
+	if options != nil {
+		s.options = *options
+	}
+	s.options.SkipSymlinks = true
+	s.options.Progress = nil
+	return s
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted or ctx is cancelled
+func (s *MCPServer) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			if response := s.handleMessage(ctx, line); response != nil {
+				if err := encoder.Encode(response); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *MCPServer) handleMessage(ctx context.Context, line []byte) *rpcResponse {
+	var request rpcRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()}}
+	}
+
+	// Notifications carry no id and never get a response
+	if len(request.ID) == 0 {
+		return nil
+	}
+
+	response := &rpcResponse{JSONRPC: "2.0", ID: request.ID}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		response.Error = &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}
+		return response
+	}
+
+	switch request.Method {
+	case "initialize":
+		response.Result = s.initialize(request.Params)
+	case "ping":
+		response.Result = struct{}{}
+	case "tools/list":
+		response.Result = map[string]interface{}{"tools": mcpTools()}
+	case "tools/call":
+		result, err := s.callTool(ctx, request.Params)
+		if err != nil {
+			response.Error = err
+		} else {
+			response.Result = result
+		}
+	default:
+		response.Error = &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + request.Method}
+	}
+
+	return response
+}
+
+func (s *MCPServer) initialize(params json.RawMessage) interface{} {
+	var request struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(params, &request)
+
+	// Agree to the client's revision when supported, otherwise offer ours
+	// and let the client decide whether to continue
+	version := MCPProtocolVersion
+	for _, supported := range mcpProtocolVersions {
+		if request.ProtocolVersion == supported {
+			version = supported
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "code2txt",
+			"version": s.version,
+		},
+		"instructions": "Read-only access to the repository at " + filepath.Base(s.root) +
+			". Start with get_tree or stats, then read_files with a token budget.",
+	}
+}
+
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+func mcpTools() []mcpTool {
+	stringProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	intProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "integer", "description": description}
+	}
+	boolProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "boolean", "description": description}
+	}
+	schema := func(properties map[string]interface{}, required ...string) map[string]interface{} {
+		s := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+
+	return []mcpTool{
+		{
+			Name:        "get_tree",
+			Description: "Show the directory tree of the repository with token counts per file.",
+			InputSchema: schema(map[string]interface{}{
+				"path":   stringProp("Subdirectory to show, relative to the repository root"),
+				"tokens": boolProp("Show token counts (default true)"),
+			}),
+		},
+		{
+			Name:        "read_files",
+			Description: "Read files or whole directories. Files are added in path order until the token budget is used up.",
+			InputSchema: schema(map[string]interface{}{
+				"paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Files or directories relative to the repository root",
+				},
+				"budget": intProp("Maximum number of tokens to return (0 = no limit)"),
+				"format": stringProp("Output format: text, markdown or xml (default text)"),
+			}, "paths"),
+		},
+		{
+			Name:        "search",
+			Description: "Search file contents and return matching lines as path:line: text.",
+			InputSchema: schema(map[string]interface{}{
+				"query":       stringProp("Text to search for"),
+				"regex":       boolProp("Treat query as a regular expression"),
+				"include":     stringProp("Only search files whose name matches this glob, e.g. *.go"),
+				"max_results": intProp("Maximum number of matching lines (default 50)"),
+			}, "query"),
+		},
+		{
+			Name:        "stats",
+			Description: "Count files, directories and tokens in the repository.",
+			InputSchema: schema(map[string]interface{}{}),
+		},
+	}
+}
+
+type toolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type toolArguments struct {
+	Path       string   `json:"path"`
+	Tokens     *bool    `json:"tokens"`
+	Paths      []string `json:"paths"`
+	Budget     int      `json:"budget"`
+	Format     string   `json:"format"`
+	Query      string   `json:"query"`
+	Regex      bool     `json:"regex"`
+	Include    string   `json:"include"`
+	MaxResults int      `json:"max_results"`
+}
+
+// callTool runs a tool. Tool failures are reported to the model as a
+// result with isError set; only malformed calls are protocol errors.
+func (s *MCPServer) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call toolCall
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+
+	var args toolArguments
+	if len(call.Arguments) > 0 {
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid arguments: " + err.Error()}
+		}
+	}
+
+	var text string
+	var err error
+	switch call.Name {
+	case "get_tree":
+		text, err = s.getTree(ctx, args)
+	case "read_files":
+		text, err = s.readFiles(ctx, args)
+	case "search":
+		text, err = s.search(ctx, args)
+	case "stats":
+		text, err = s.stats(ctx)
+	default:
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + call.Name}
+	}
+
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	return toolResult(text, false), nil
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// scan scans the whole root with the server's options
+func (s *MCPServer) scan(ctx context.Context) (*ScanResult, error) {
+	options := s.options
+	return NewScanner(&options).ScanDirectoryContext(ctx, s.root)
+}
+
+// cleanRelPath validates a path argument and returns it in OS form
+func cleanRelPath(path string) (string, error) {
+	if path == "" || path == "." || path == "/" {
+		return ".", nil
+	}
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) {
+		return "", fmt.Errorf("path must be relative to the repository root: %s", path)
+	}
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes the repository root: %s", path)
+	}
+	return clean, nil
+}
+
+// selectFiles keeps the files of result that are, or are inside, one of
+// the given paths. It returns the paths that matched nothing.
+func selectFiles(result *ScanResult, paths []string) ([]*FileInfo, []string) {
+	selected := make(map[*FileInfo]bool)
+	missing := make([]string, 0)
+
+	for _, path := range paths {
+		clean, err := cleanRelPath(path)
+		if err != nil {
+			missing = append(missing, path)
+			continue
+		}
+
+		found := false
+		for _, file := range result.Files {
+			if file.IsDirectory {
+				continue
+			}
+			if clean == "." || file.RelativePath == clean ||
+				strings.HasPrefix(file.RelativePath, clean+string(filepath.Separator)) {
+				selected[file] = true
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, path)
+		}
+	}
+
+	files := make([]*FileInfo, 0, len(selected))
+	for _, file := range result.Files {
+		if selected[file] {
+			files = append(files, file)
+		}
+	}
+	return files, missing
+}
+
+func (s *MCPServer) getTree(ctx context.Context, args toolArguments) (string, error) {
+	result, err := s.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if clean, err := cleanRelPath(args.Path); err != nil {
+		return "", err
+	} else if clean != "." {
+		files, missing := selectFiles(result, []string{args.Path})
+		if len(missing) > 0 {
+			return "", fmt.Errorf("no files found in %s", args.Path)
+		}
+		result = subResult(result, files)
+	}
+
+	showTokens := args.Tokens == nil || *args.Tokens
+	return RenderTree(BuildTree(result), showTokens), nil
+}
+
+func (s *MCPServer) readFiles(ctx context.Context, args toolArguments) (string, error) {
+	if len(args.Paths) == 0 {
+		return "", fmt.Errorf("paths is required")
+	}
+	if args.Format == "json" {
+		return "", fmt.Errorf("format must be text, markdown or xml")
+	}
+	if err := ValidateFormat(args.Format); err != nil {
+		return "", err
+	}
+
+	result, err := s.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	files, missing := selectFiles(result, args.Paths)
+	selected := subResult(result, files)
+	omitted := ApplyTokenBudget(selected, args.Budget)
+
+	formatter := NewOutputFormatter(&OutputOptions{
+		ShowTokens: true,
+		Format:     args.Format,
+	})
+
+	var output strings.Builder
+	output.WriteString(formatter.FormatOutput(selected))
+	if len(omitted) > 0 {
+		output.WriteString(fmt.Sprintf("\nOmitted to stay within %d tokens:\n", args.Budget))
+		for _, file := range omitted {
+			output.WriteString(fmt.Sprintf("  %s (%d tokens)\n", filepath.ToSlash(file.RelativePath), file.TokenCount))
+		}
+	}
+	if len(missing) > 0 {
+		output.WriteString("\nNot found or excluded:\n")
+		for _, path := range missing {
+			output.WriteString("  " + path + "\n")
+		}
+	}
+	return output.String(), nil
+}
+
+func (s *MCPServer) search(ctx context.Context, args toolArguments) (string, error) {
+	if args.Query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	var matches func(string) bool
+	if args.Regex {
+		re, err := regexp.Compile(args.Query)
+		if err != nil {
+			return "", fmt.Errorf("invalid regular expression: %v", err)
+		}
+		matches = re.MatchString
+	} else {
+		matches = func(line string) bool { return strings.Contains(line, args.Query) }
+	}
+
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = 50
+	}
+
+	result, err := s.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	count := 0
+	for _, file := range contentFiles(result) {
+		if args.Include != "" {
+			if matched, _ := filepath.Match(args.Include, filepath.Base(file.RelativePath)); !matched {
+				continue
+			}
+		}
+
+		for i, line := range strings.Split(file.Content, "\n") {
+			if !matches(line) {
+				continue
+			}
+			if count == maxResults {
+				output.WriteString(fmt.Sprintf("(stopped after %d results)\n", maxResults))
+				return output.String(), nil
+			}
+			output.WriteString(fmt.Sprintf("%s:%d: %s\n", filepath.ToSlash(file.RelativePath), i+1, strings.TrimSpace(line)))
+			count++
+		}
+	}
+
+	if count == 0 {
+		return "No matches found.", nil
+	}
+	return output.String(), nil
+}
+
+func (s *MCPServer) stats(ctx context.Context) (string, error) {
+	result, err := s.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	files, directories := GetTreeStats(BuildTree(result))
+
+	// Largest files first, they matter most for the token budget
+	largest := contentFiles(result)
+	sort.SliceStable(largest, func(i, j int) bool {
+		return largest[i].TokenCount > largest[j].TokenCount
+	})
+	if len(largest) > 10 {
+		largest = largest[:10]
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Files: %d\n", files))
+	output.WriteString(fmt.Sprintf("Directories: %d\n", directories))
+	output.WriteString(fmt.Sprintf("Total tokens: %d (%s)\n", result.TotalTokens, GetTokenCountSummary(result.TotalTokens)))
+	output.WriteString("Languages:\n")
+	for _, stat := range LanguageStats(result) {
+		output.WriteString(fmt.Sprintf("  %s: %d files, %d tokens\n", stat.Language, stat.Files, stat.Tokens))
+	}
+	output.WriteString("Largest files:\n")
+	for _, file := range largest {
+		output.WriteString(fmt.Sprintf("  %s (%d tokens)\n", filepath.ToSlash(file.RelativePath), file.TokenCount))
+	}
+	return output.String(), nil
+}
+
+// subResult creates a result holding only files, with fresh totals
+func subResult(result *ScanResult, files []*FileInfo) *ScanResult {
+	sub := &ScanResult{RootPath: result.RootPath, Files: files}
+	for _, file := range files {
+		sub.TotalFiles++
+		sub.TotalTokens += file.TokenCount
+	}
+	return sub
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// mcpSession runs a scripted client against a server and returns the
+// responses keyed by request id
+func mcpSession(t *testing.T, server *MCPServer, requests ...string) map[float64]map[string]interface{} {
+	t.Helper()
+
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	var out bytes.Buffer
+	if err := server.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	responses := make(map[float64]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			t.Fatalf("Invalid response %q: %v", line, err)
+		}
+		id, _ := response["id"].(float64)
+		responses[id] = response
+	}
+	return responses
+}
+
+func toolText(t *testing.T, response map[string]interface{}) (string, bool) {
+	t.Helper()
+	result, ok := response["result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a tool result, got %v", response)
+	}
+	content := result["content"].([]interface{})[0].(map[string]interface{})
+	return content["text"].(string), result["isError"].(bool)
+}
+
+func newTestMCPServer(t *testing.T) *MCPServer {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":        "package main\n\nfunc main() {\n\tserve()\n}\n",
+		"api/server.go":  "package api\n\nconst apiKey = \"sk-abcdefghijklmnopqrstuvwxyz123456\"\n\nfunc serve() {}\n",
+		"docs/guide.md":  "# Guide\n\nCall serve() to start.\n",
+		".env":           "DATABASE_PASSWORD=hunter2hunter2\n",
+		"build/out.log":  "noise\n",
+		"vendor/lib.go":  "package lib\n",
+		"api/server.txt": "plain notes\n",
+	})
+
+	return NewMCPServer(root, &ScanOptions{
+		ExcludePatterns: []string{"*.log", "vendor"},
+		Redact:          true,
+	}, "test")
+}
+
+func TestMCPProtocol(t *testing.T) {
+	server := newTestMCPServer(t)
+
+	responses := mcpSession(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`not json`,
+	)
+
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses (no reply to the notification), got %d", len(responses))
+	}
+
+	info := responses[1]["result"].(map[string]interface{})
+	if info["protocolVersion"] != "2024-11-05" {
+		t.Errorf("Unexpected protocol version %v", info["protocolVersion"])
+	}
+
+	tools := responses[2]["result"].(map[string]interface{})["tools"].([]interface{})
+	names := make([]string, 0)
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "get_tree,read_files,search,stats" {
+		t.Errorf("Unexpected tools %v", names)
+	}
+
+	if code := responses[4]["error"].(map[string]interface{})["code"]; code != float64(rpcMethodNotFound) {
+		t.Errorf("Expected method not found, got %v", code)
+	}
+	if code := responses[0]["error"].(map[string]interface{})["code"]; code != float64(rpcParseError) {
+		t.Errorf("Expected parse error, got %v", code)
+	}
+}
+
+func TestMCPProtocolVersion(t *testing.T) {
+	server := newTestMCPServer(t)
+
+	responses := mcpSession(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2099-01-01","capabilities":{}}}`,
+	)
+
+	info := responses[1]["result"].(map[string]interface{})
+	if info["protocolVersion"] != MCPProtocolVersion {
+		t.Errorf("Expected the server's version for an unknown one, got %v", info["protocolVersion"])
+	}
+}
+
+func TestMCPTools(t *testing.T) {
+	server := newTestMCPServer(t)
+
+	responses := mcpSession(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_tree","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"read_files","arguments":{"paths":["api"]}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search","arguments":{"query":"serve()"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"stats","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"read_files","arguments":{"paths":["../etc/passwd",".env","vendor/lib.go"]}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"read_files","arguments":{"paths":["."],"budget":12}}}`,
+	)
+
+	tree, _ := toolText(t, responses[1])
+	if !strings.Contains(tree, "main.go") || strings.Contains(tree, "vendor") || strings.Contains(tree, ".env") {
+		t.Errorf("Expected excluded and sensitive files to be hidden from the tree:\n%s", tree)
+	}
+
+	files, isError := toolText(t, responses[2])
+	if isError || !strings.Contains(files, "File: api/server.go") || !strings.Contains(files, "File: api/server.txt") {
+		t.Errorf("Expected both api files, got:\n%s", files)
+	}
+	if strings.Contains(files, "sk-abcdef") || !strings.Contains(files, RedactedPlaceholder) {
+		t.Errorf("Expected the API key to be redacted, got:\n%s", files)
+	}
+
+	matches, _ := toolText(t, responses[3])
+	for _, expected := range []string{"main.go:4: serve()", "api/server.go:5: func serve() {}", "docs/guide.md:3: Call serve() to start."} {
+		if !strings.Contains(matches, expected) {
+			t.Errorf("Expected search result %q in:\n%s", expected, matches)
+		}
+	}
+
+	stats, _ := toolText(t, responses[4])
+	if !strings.Contains(stats, "Files: 4") {
+		t.Errorf("Expected 4 files in stats, got:\n%s", stats)
+	}
+
+	denied, _ := toolText(t, responses[5])
+	if strings.Contains(denied, "hunter2") || strings.Contains(denied, "package lib") {
+		t.Errorf("Expected sensitive and excluded files to be unreadable, got:\n%s", denied)
+	}
+	if !strings.Contains(denied, "Not found or excluded:\n  ../etc/passwd\n  .env\n  vendor/lib.go") {
+		t.Errorf("Expected all three paths to be reported, got:\n%s", denied)
+	}
+
+	budgeted, _ := toolText(t, responses[6])
+	if !strings.Contains(budgeted, "Omitted to stay within 12 tokens") {
+		t.Errorf("Expected budget note, got:\n%s", budgeted)
+	}
+}
@@ -2,12 +2,53 @@ package internal
 
 import (
 	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 )
 
 type OutputOptions struct {
 	ShowTokens bool
 	ShowTree   bool
+	// Format selects the layout: text (default), markdown, xml or json
+	Format string
+	// TreeDepth limits the levels of the rendered tree, 0 shows all
+	TreeDepth int
+	// CollapseThreshold folds the files of directories holding more than
+	// this many into one tree line, see TreeOptions.Collapse
+	CollapseThreshold int
+	// LineNumbers prefixes every content line with its line number
+	LineNumbers bool
+	// Template renders the output instead of Format when set, see
+	// ParseTemplate
+	Template *template.Template
+	// TemplateData converts the data Template is executed with, for
+	// callers that expose their own type for it
+	TemplateData func(*TemplateData) any
+	// Prompt wraps the dump in instructions, its tokens count towards the
+	// totals
+	Prompt *Prompt
+}
+
+// OutputFormats lists the values accepted by OutputOptions.Format
+func OutputFormats() []string {
+	return []string{"text", "markdown", "xml", "json"}
+}
+
+// ValidateFormat checks that format is one of OutputFormats
+func ValidateFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, known := range OutputFormats() {
+		if format == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (available: %s)",
+		format, strings.Join(OutputFormats(), ", "))
 }
 
 type OutputFormatter struct {
@@ -29,30 +70,75 @@ func NewOutputFormatter(options *OutputOptions) *OutputFormatter {
 
 func (f *OutputFormatter) FormatOutput(result *ScanResult) string {
 	var output strings.Builder
+	f.WriteOutput(&output, result)
+	return output.String()
+}
 
-	// Generate tree structure if enabled
-	if f.options.ShowTree {
-		output.WriteString("Directory Structure:\n")
-		tree := BuildTree(result)
-		treeOutput := RenderTree(tree, f.options.ShowTokens)
-		output.WriteString(treeOutput)
-		output.WriteString("\n")
-
-		// Add summary statistics
-		if f.options.ShowTokens {
-			output.WriteString(fmt.Sprintf("Total: %s tokens (%s)\n\n",
-				formatNumber(result.TotalTokens),
-				GetTokenCountSummary(result.TotalTokens)))
-		} else {
-			output.WriteString(fmt.Sprintf("Total files: %d\n\n", result.TotalFiles))
+// Prepare returns result as it will be rendered. With line numbers this is
+// a copy whose token counts include the numbers, so a token budget applied
+// to it matches the output.
+func (f *OutputFormatter) Prepare(result *ScanResult) *ScanResult {
+	if f.options.LineNumbers {
+		return numberedResult(result)
+	}
+	return result
+}
+
+// WriteOutput writes the output for result to w one file at a time, so
+// large dumps can be streamed
+func (f *OutputFormatter) WriteOutput(w io.Writer, result *ScanResult) error {
+	if err := ValidateFormat(f.options.Format); err != nil {
+		return err
+	}
+
+	result = f.Prepare(result)
+	if f.options.Template != nil {
+		var data any = f.templateData(result)
+		if f.options.TemplateData != nil {
+			data = f.options.TemplateData(data.(*TemplateData))
 		}
+		return f.options.Template.Execute(w, data)
+	}
+
+	switch f.options.Format {
+	case "json":
+		output := &errWriter{w: w}
+		f.writeJSON(output, result)
+		return output.err
+	case "":
+		return f.writeTemplate(w, parsedTemplates["text"], result)
+	default:
+		return f.writeTemplate(w, parsedTemplates[f.options.Format], result)
 	}
+}
 
-	// Generate file contents section
-	output.WriteString("File Contents:\n")
-	output.WriteString(strings.Repeat("=", 50) + "\n\n")
+// treeOptions returns how the tree of the output is rendered
+func (f *OutputFormatter) treeOptions() TreeOptions {
+	return TreeOptions{
+		ShowTokens: f.options.ShowTokens,
+		MaxDepth:   f.options.TreeDepth,
+		Collapse:   f.options.CollapseThreshold,
+	}
+}
+
+// renderTrees renders the directory tree of result, with a separate tree
+// for every root of a multi-root scan
+func renderTrees(result *ScanResult, options TreeOptions) string {
+	trees := make([]string, 0, len(result.Roots)+1)
+	for _, section := range SplitRoots(result) {
+		tree := BuildTree(section)
+		// Name nested roots by their full path, e.g. services/api
+		if rel, err := filepath.Rel(result.RootPath, section.RootPath); err == nil && rel != "." {
+			tree.Name = filepath.ToSlash(rel)
+		}
+		trees = append(trees, RenderTreeOptions(tree, options))
+	}
+	return strings.Join(trees, "\n")
+}
 
-	// Get only files (not directories) and sort them
+// contentFiles returns the files of result, without directories, sorted by
+// relative path
+func contentFiles(result *ScanResult) []*FileInfo {
 	files := make([]*FileInfo, 0)
 	for _, file := range result.Files {
 		if !file.IsDirectory {
@@ -62,45 +148,46 @@ func (f *OutputFormatter) FormatOutput(result *ScanResult) string {
 
 	// Sort files by relative path
 	sortFiles(files)
+	return files
+}
 
+// FilePaths returns the relative paths of the files in result, without
+// directories, sorted like the output
+func FilePaths(result *ScanResult) []string {
+	files := contentFiles(result)
+	paths := make([]string, len(files))
 	for i, file := range files {
-		if i > 0 {
-			output.WriteString("\n")
-		}
+		paths[i] = file.RelativePath
+	}
+	return paths
+}
 
-		// File header
-		header := fmt.Sprintf("File: %s", file.RelativePath)
-		if f.options.ShowTokens {
-			header += fmt.Sprintf(" (%d tokens)", file.TokenCount)
-		}
-		output.WriteString(header + "\n")
-		output.WriteString(strings.Repeat("-", len(header)) + "\n")
-
-		// File content
-		if file.Content != "" {
-			output.WriteString(file.Content)
-			// Ensure file ends with newline
-			if !strings.HasSuffix(file.Content, "\n") {
-				output.WriteString("\n")
-			}
-		} else {
-			output.WriteString("(empty file)\n")
-		}
+// errWriter remembers the first write error so formatters can write
+// without checking every call
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) WriteString(s string) {
+	if ew.err != nil {
+		return
 	}
+	_, ew.err = io.WriteString(ew.w, s)
+}
 
-	return output.String()
+// incompleteMarker warns that a dump only covers part of the tree
+func incompleteMarker(result *ScanResult) string {
+	return fmt.Sprintf("WARNING: INCOMPLETE OUTPUT - the scan was interrupted after %d files\n\n",
+		result.TotalFiles)
 }
 
+// sortFiles sorts files by slash-separated path, so the order is the same
+// on every platform and independent of the walk order
 func sortFiles(files []*FileInfo) {
-	// Simple bubble sort for file paths
-	n := len(files)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if files[j].RelativePath > files[j+1].RelativePath {
-				files[j], files[j+1] = files[j+1], files[j]
-			}
-		}
-	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return filepath.ToSlash(files[i].RelativePath) < filepath.ToSlash(files[j].RelativePath)
+	})
 }
 
 func formatNumber(num int) string {
@@ -120,11 +207,9 @@ func (f *OutputFormatter) FormatFileList(result *ScanResult) string {
 	output.WriteString("Files and Token Counts:\n")
 	output.WriteString(strings.Repeat("=", 30) + "\n")
 
-	for _, file := range result.Files {
-		if !file.IsDirectory {
-			line := fmt.Sprintf("%-40s %6d tokens\n", file.RelativePath, file.TokenCount)
-			output.WriteString(line)
-		}
+	for _, file := range contentFiles(result) {
+		line := fmt.Sprintf("%-40s %6d tokens\n", file.RelativePath, file.TokenCount)
+		output.WriteString(line)
 	}
 
 	output.WriteString(strings.Repeat("-", 50) + "\n")
@@ -134,16 +219,27 @@ func (f *OutputFormatter) FormatFileList(result *ScanResult) string {
 	return output.String()
 }
 
-// FormatSummary creates a brief summary of the scan results
+// FormatSummary creates a brief summary of the scan results. Token totals
+// are left out when the files were listed without reading them.
 func (f *OutputFormatter) FormatSummary(result *ScanResult) string {
 	var output strings.Builder
 
+	_, directories := GetTreeStats(BuildTree(result))
+	var size int64
+	for _, file := range result.Files {
+		size += file.Size
+	}
+
 	output.WriteString("Scan Summary:\n")
 	output.WriteString(fmt.Sprintf("Root Path: %s\n", result.RootPath))
 	output.WriteString(fmt.Sprintf("Files: %d\n", result.TotalFiles))
-	output.WriteString(fmt.Sprintf("Total Tokens: %s (%s)\n",
-		formatNumber(result.TotalTokens),
-		GetTokenCountSummary(result.TotalTokens)))
+	output.WriteString(fmt.Sprintf("Directories: %d\n", directories))
+	output.WriteString(fmt.Sprintf("Size: %s\n", formatBytes(size)))
+	if !result.NamesOnly {
+		output.WriteString(fmt.Sprintf("Total Tokens: %s (%s)\n",
+			formatNumber(result.TotalTokens+f.options.Prompt.Tokens()),
+			GetTokenCountSummary(result.TotalTokens+f.options.Prompt.Tokens())))
+	}
 
 	return output.String()
 }
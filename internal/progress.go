@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ScanProgress is a snapshot of a running scan
+type ScanProgress struct {
+	FilesScanned int
+	// TotalFiles is the number of files the scan will visit, counted
+	// before it starts; zero when unknown
+	TotalFiles    int
+	BytesRead     int64
+	TokensCounted int
+	CurrentPath   string
+	Done          bool
+}
+
+// ProgressFunc receives progress updates from ScanDirectory. It is called
+// synchronously from the scan, so it should return quickly.
+type ProgressFunc func(ScanProgress)
+
+// ProgressReporter renders scan progress either as a live status line on a
+// terminal or as periodic log lines otherwise. The status line shows a bar
+// once the total number of files is known, and a spinner before that.
+type ProgressReporter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	live     bool
+	interval time.Duration
+	last     time.Time
+	frame    int
+	printed  bool
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// NewProgressReporter creates a reporter writing to w. Live reporters
+// redraw a single line and should only be used on terminals.
+func NewProgressReporter(w io.Writer, live bool) *ProgressReporter {
+	interval := 2 * time.Second
+	if live {
+		interval = 100 * time.Millisecond
+	}
+
+	return &ProgressReporter{
+		w:        w,
+		live:     live,
+		interval: interval,
+		last:     time.Now(),
+	}
+}
+
+// Report renders p if enough time has passed since the last update
+func (r *ProgressReporter) Report(p ScanProgress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p.Done {
+		r.finish(p)
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(r.last) < r.interval {
+		return
+	}
+	r.last = now
+	r.printed = true
+
+	if r.live {
+		var line string
+		if p.TotalFiles > 0 {
+			line = fmt.Sprintf("%s Scanning %s", progressBar(p.FilesScanned, p.TotalFiles), formatProgress(p))
+		} else {
+			r.frame = (r.frame + 1) % len(spinnerFrames)
+			line = fmt.Sprintf("%s Scanning %s", spinnerFrames[r.frame], formatProgress(p))
+		}
+		if p.CurrentPath != "" {
+			line += " · " + truncatePath(p.CurrentPath, 40)
+		}
+		fmt.Fprintf(r.w, "\r\033[K%s", line)
+	} else {
+		fmt.Fprintf(r.w, "Scanning: %s, at %s\n", formatProgress(p), p.CurrentPath)
+	}
+}
+
+func (r *ProgressReporter) finish(p ScanProgress) {
+	if !r.printed {
+		return
+	}
+
+	if r.live {
+		// Clear the status line so it does not mix with later output
+		fmt.Fprint(r.w, "\r\033[K")
+	} else {
+		fmt.Fprintf(r.w, "Scan complete: %s\n", formatProgress(p))
+	}
+	r.printed = false
+}
+
+func formatProgress(p ScanProgress) string {
+	files := fmt.Sprintf("%d files", p.FilesScanned)
+	if p.TotalFiles > 0 {
+		files = fmt.Sprintf("%d/%d files", p.FilesScanned, p.TotalFiles)
+	}
+	return fmt.Sprintf("%s, %s, %s tokens",
+		files, formatBytes(p.BytesRead), formatNumber(p.TokensCounted))
+}
+
+// progressBarWidth is the number of cells in the live progress bar
+const progressBarWidth = 20
+
+// progressBar renders done out of total as a bar and a percentage
+func progressBar(done, total int) string {
+	percent := min(done*100/total, 100)
+	filled := percent * progressBarWidth / 100
+	return fmt.Sprintf("[%s%s] %3d%%",
+		strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), percent)
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// truncatePath shortens a path to at most max characters, keeping the end
+// which usually carries the most information
+func truncatePath(path string, max int) string {
+	if utf8.RuneCountInString(path) <= max {
+		return path
+	}
+	runes := []rune(path)
+	return "…" + strings.TrimLeft(string(runes[len(runes)-max+1:]), "/\\")
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanDirectoryProgress(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.go":     "package a\n",
+		"b.go":     "package b\n",
+		"sub/c.go": "package c\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	var updates []ScanProgress
+	scanner := NewScanner(&ScanOptions{
+		Progress: func(p ScanProgress) { updates = append(updates, p) },
+	})
+	if _, err := scanner.ScanDirectory(tempDir); err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+
+	if len(updates) != 4 {
+		t.Fatalf("Expected 3 file updates and a final update, got %d", len(updates))
+	}
+
+	final := updates[len(updates)-1]
+	if !final.Done {
+		t.Error("Expected final update to be marked done")
+	}
+	if final.FilesScanned != 3 {
+		t.Errorf("Expected 3 files scanned, got %d", final.FilesScanned)
+	}
+	if updates[0].TotalFiles != 3 {
+		t.Errorf("Expected the total to be known from the first update, got %d", updates[0].TotalFiles)
+	}
+	if final.BytesRead != 30 {
+		t.Errorf("Expected 30 bytes read, got %d", final.BytesRead)
+	}
+	if final.TokensCounted == 0 {
+		t.Error("Expected tokens to be counted")
+	}
+}
+
+func TestProgressReporterLogLines(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewProgressReporter(&buf, false)
+	reporter.interval = 0
+
+	reporter.Report(ScanProgress{FilesScanned: 1, BytesRead: 2048, TokensCounted: 10, CurrentPath: "main.go"})
+	reporter.Report(ScanProgress{FilesScanned: 1, BytesRead: 2048, TokensCounted: 10, Done: true})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %q", buf.String())
+	}
+	if lines[0] != "Scanning: 1 files, 2.0 KB, 10 tokens, at main.go" {
+		t.Errorf("Unexpected progress line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "Scan complete:") {
+		t.Errorf("Unexpected final line %q", lines[1])
+	}
+}
+
+func TestProgressReporterLive(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewProgressReporter(&buf, true)
+	reporter.interval = 0
+
+	reporter.Report(ScanProgress{FilesScanned: 5, CurrentPath: "src/app.go"})
+	reporter.Report(ScanProgress{Done: true})
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "\r\033[K") || !strings.Contains(output, "src/app.go") {
+		t.Errorf("Expected live status line, got %q", output)
+	}
+	if !strings.HasSuffix(output, "\r\033[K") {
+		t.Errorf("Expected status line to be cleared at the end, got %q", output)
+	}
+}
+
+func TestProgressReporterBar(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewProgressReporter(&buf, true)
+	reporter.interval = 0
+
+	reporter.Report(ScanProgress{FilesScanned: 5, TotalFiles: 20, CurrentPath: "src/app.go"})
+	if output := buf.String(); !strings.Contains(output, "[█████░░░░░░░░░░░░░░░]  25% Scanning 5/20 files") {
+		t.Errorf("Expected a progress bar, got %q", output)
+	}
+
+	if bar := progressBar(30, 20); bar != "["+strings.Repeat("█", progressBarWidth)+"] 100%" {
+		t.Errorf("Expected the bar to stop at 100%%, got %q", bar)
+	}
+}
+
+func TestTruncatePath(t *testing.T) {
+	if got := truncatePath("short.go", 20); got != "short.go" {
+		t.Errorf("Expected path to be unchanged, got %q", got)
+	}
+	if got := truncatePath("very/long/path/to/some/file.go", 12); got != "…ome/file.go" {
+		t.Errorf("Unexpected truncated path %q", got)
+	}
+}
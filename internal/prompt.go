@@ -0,0 +1,132 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed prompts.json
+var promptsJSON []byte
+
+// Prompt is text written before and after a dump, such as instructions
+// for the model and the question to answer
+type Prompt struct {
+	Header string `json:"header,omitempty"`
+	Footer string `json:"footer,omitempty"`
+}
+
+// Tokens returns the estimated token cost of the header and footer
+func (p *Prompt) Tokens() int {
+	if p == nil {
+		return 0
+	}
+	return CountTokens(p.Header) + CountTokens(p.Footer)
+}
+
+// DefaultConfigDir returns the code2txt directory inside the user config
+// dir, or $CODE2TXT_CONFIG_DIR when set
+func DefaultConfigDir() (string, error) {
+	if dir := os.Getenv("CODE2TXT_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "code2txt"), nil
+}
+
+// LoadPrompts returns the built-in prompt presets merged with the presets
+// in prompts.json inside configDir, which override built-ins of the same
+// name. A missing file is not an error.
+func LoadPrompts(configDir string) (map[string]Prompt, error) {
+	presets := make(map[string]Prompt)
+	if err := json.Unmarshal(promptsJSON, &presets); err != nil {
+		panic(fmt.Sprintf("invalid prompts.json: %v", err))
+	}
+	if configDir == "" {
+		return presets, nil
+	}
+
+	path := filepath.Join(configDir, "prompts.json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return presets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var custom map[string]Prompt
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	for name, prompt := range custom {
+		presets[name] = prompt
+	}
+	return presets, nil
+}
+
+// PromptNames returns the names of presets, sorted
+func PromptNames(presets map[string]Prompt) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadPromptText returns the content of the file at value, or value
+// itself when it is text. A value that looks like a file name, such as
+// rules.md or prompts/review, but does not exist is an error rather than
+// being sent as the prompt.
+func ReadPromptText(value string) (string, error) {
+	info, err := os.Stat(value)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid):
+		if looksLikePath(value) {
+			return "", fmt.Errorf("prompt file not found: %s", value)
+		}
+		return value, nil
+	case err != nil:
+		return "", err
+	case info.IsDir():
+		return "", fmt.Errorf("%s is a directory", value)
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// looksLikePath reports whether value is a file name rather than prompt
+// text: a single word with a directory or a short file extension
+func looksLikePath(value string) bool {
+	if value == "" || strings.ContainsAny(value, " \t\r\n") {
+		return false
+	}
+	if strings.ContainsRune(value, '/') || strings.ContainsRune(value, filepath.Separator) {
+		return true
+	}
+	ext := strings.TrimPrefix(filepath.Ext(value), ".")
+	if ext == "" || len(ext) > 5 {
+		return false
+	}
+	for _, r := range ext {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
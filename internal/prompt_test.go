@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrompts(t *testing.T) {
+	dir := t.TempDir()
+	presets, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"review", "explain", "document", "find-bugs", "write-tests"} {
+		if presets[name].Header == "" || presets[name].Footer == "" {
+			t.Errorf("Expected a built-in %s preset, got %+v", name, presets[name])
+		}
+	}
+
+	custom := `{"review": {"header": "Be brief."}, "security": {"footer": "Any injections?"}}`
+	if err := os.WriteFile(filepath.Join(dir, "prompts.json"), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	presets, err = LoadPrompts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presets["review"].Header != "Be brief." || presets["security"].Footer != "Any injections?" {
+		t.Errorf("Expected the config presets to override the built-ins, got %+v", presets)
+	}
+	if len(presets["explain"].Footer) == 0 {
+		t.Error("Expected the other built-in presets to be kept")
+	}
+
+	os.WriteFile(filepath.Join(dir, "prompts.json"), []byte("{"), 0644)
+	if _, err := LoadPrompts(dir); err == nil {
+		t.Error("Expected an error for an invalid prompts.json")
+	}
+}
+
+func TestReadPromptText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "question.txt")
+	os.WriteFile(path, []byte("Why?\n"), 0644)
+
+	if text, _ := ReadPromptText(path); text != "Why?" {
+		t.Errorf("Expected the file content, got %q", text)
+	}
+	if text, _ := ReadPromptText("What does main do?"); text != "What does main do?" {
+		t.Errorf("Expected the text itself, got %q", text)
+	}
+	if text, err := ReadPromptText("Fix it."); err != nil || text != "Fix it." {
+		t.Errorf("Expected a sentence to be text, got %q, %v", text, err)
+	}
+
+	// A misspelled file must not be sent as the prompt
+	for _, missing := range []string{"revew.md", filepath.Join(t.TempDir(), "rules"), filepath.Dir(path)} {
+		if text, err := ReadPromptText(missing); err == nil {
+			t.Errorf("Expected an error for %s, got the text %q", missing, text)
+		}
+	}
+}
+
+func TestPromptOutput(t *testing.T) {
+	prompt := &Prompt{Header: "You are reviewing this codebase.", Footer: "Where is the bug?"}
+	result := formatTestResult()
+
+	formatter := NewOutputFormatter(&OutputOptions{ShowTokens: true, ShowTree: true, Prompt: prompt})
+	output := formatter.FormatOutput(result)
+	if !strings.HasPrefix(output, prompt.Header+"\n\n") || !strings.HasSuffix(output, "\n"+prompt.Footer+"\n") {
+		t.Errorf("Expected the dump wrapped in the prompt, got:\n%s", output)
+	}
+
+	total := result.TotalTokens + prompt.Tokens()
+	if !strings.Contains(output, "Total: "+formatNumber(total)+" tokens") {
+		t.Errorf("Expected a total of %d tokens including the prompt, got:\n%s", total, output)
+	}
+}
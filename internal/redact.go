@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RedactedPlaceholder replaces secrets found in file contents
+const RedactedPlaceholder = "[REDACTED]"
+
+// sensitiveFilePatterns match files that hold credentials as a whole and
+// are skipped entirely when redaction is enabled
+var sensitiveFilePatterns = []string{
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "*.kdbx",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".netrc", ".pgpass", ".htpasswd",
+}
+
+// secretPatterns match secrets embedded in otherwise harmless files. When a
+// pattern has a "value" group only that part is replaced, so the key name
+// stays visible.
+var secretPatterns = []*regexp.Regexp{
+	// PEM private key blocks
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// GitHub tokens
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	// Slack tokens
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+	// OpenAI and Anthropic style API keys
+	regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}\b`),
+	// Values assigned to secret-looking keys, quoted or not
+	regexp.MustCompile(`(?i)(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?(?P<value>[^"'\s]{8,})["']?`),
+}
+
+// IsSensitiveFile reports whether the file at path typically holds
+// credentials, such as .env files and private keys
+func IsSensitiveFile(path string) bool {
+	name := filepath.Base(path)
+	for _, pattern := range sensitiveFilePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecrets replaces credentials found in content with
+// RedactedPlaceholder and returns the new content and the number of
+// replacements
+func RedactSecrets(content string) (string, int) {
+	count := 0
+
+	for _, pattern := range secretPatterns {
+		valueGroup := pattern.SubexpIndex("value")
+
+		content = pattern.ReplaceAllStringFunc(content, func(match string) string {
+			count++
+			if valueGroup < 0 {
+				return RedactedPlaceholder
+			}
+			value := pattern.FindStringSubmatch(match)[valueGroup]
+			return strings.Replace(match, value, RedactedPlaceholder, 1)
+		})
+	}
+
+	return content, count
+}
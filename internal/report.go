@@ -0,0 +1,89 @@
+package internal
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+//go:embed report.html
+var reportPage string
+
+// reportTemplate renders a Report as a single HTML page, with its styles,
+// script and data inline so it works offline
+var reportTemplate = template.Must(template.New("report").Parse(reportPage))
+
+// Report is the data behind the HTML token report. Like the manifest it
+// holds no timestamps, so the same tree gives the same page.
+type Report struct {
+	Root        string `json:"root"`
+	TotalFiles  int    `json:"totalFiles"`
+	TotalTokens int    `json:"totalTokens"`
+	TotalSize   int64  `json:"totalSize"`
+	// Incomplete is set when the scan was interrupted
+	Incomplete bool           `json:"incomplete,omitempty"`
+	Tree       *TreeNode      `json:"tree"`
+	Files      []ReportFile   `json:"files"`
+	Languages  []LanguageStat `json:"languages"`
+	// Excluded lists what the scan left out and why, see ScanOptions.Skipped
+	Excluded []SkippedFile `json:"excluded"`
+}
+
+// ReportFile is one row of the file table of a report
+type ReportFile struct {
+	Path     string `json:"path"`
+	Language string `json:"language,omitempty"`
+	Class    string `json:"class,omitempty"`
+	Size     int64  `json:"size"`
+	Tokens   int    `json:"tokens"`
+}
+
+// SkippedFile is a file or directory left out of a scan
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// NewReport builds the report of result. skipped are the paths reported
+// through ScanOptions.Skipped during the scan.
+func NewReport(result *ScanResult, skipped []SkippedFile) *Report {
+	tree := BuildTree(result)
+	report := &Report{
+		Root:        tree.Name,
+		TotalFiles:  result.TotalFiles,
+		TotalTokens: result.TotalTokens,
+		TotalSize:   tree.TotalSize,
+		Incomplete:  result.Incomplete,
+		Tree:        tree,
+		Files:       make([]ReportFile, 0, result.TotalFiles),
+		Languages:   LanguageStats(result),
+		Excluded:    make([]SkippedFile, 0, len(skipped)),
+	}
+
+	for _, file := range contentFiles(result) {
+		report.Files = append(report.Files, ReportFile{
+			Path:     filepath.ToSlash(file.RelativePath),
+			Language: file.Language,
+			Class:    file.Class,
+			Size:     file.Size,
+			Tokens:   file.TokenCount,
+		})
+	}
+
+	for _, skip := range skipped {
+		skip.Path = filepath.ToSlash(skip.Path)
+		report.Excluded = append(report.Excluded, skip)
+	}
+	sort.SliceStable(report.Excluded, func(i, j int) bool {
+		return report.Excluded[i].Path < report.Excluded[j].Path
+	})
+
+	return report
+}
+
+// WriteReport writes report to w as a self-contained HTML page
+func WriteReport(w io.Writer, report *Report) error {
+	return reportTemplate.Execute(w, report)
+}
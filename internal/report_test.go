@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScanReportsSkipped(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":             {Data: []byte("package main\n")},
+		"main_test.go":        {Data: []byte("package main\n")},
+		"notes.txt":           {Data: []byte("notes\n")},
+		"image.bin":           {Data: []byte{0xff, 0xfe, 0x00, 0x01}},
+		"node_modules/x/a.js": {Data: []byte("module.exports = 1\n")},
+		"build/out.go":        {Data: []byte("package build\n")},
+		".gitignore":          {Data: []byte("build\n")},
+	}
+
+	skipped := make(map[string]string)
+	scanner := NewScanner(&ScanOptions{
+		IncludePatterns: []string{"*.go", "*.bin", "*.js"},
+		ExcludePatterns: []string{"node_modules", ".gitignore"},
+		SkipClasses:     []string{ClassTest},
+		Skipped: func(relPath, reason string) {
+			skipped[relPath] = reason
+		},
+	})
+	if _, err := scanner.ScanFS(context.Background(), fsys, "project"); err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	expected := map[string]string{
+		".gitignore":   SkipExcluded,
+		"node_modules": SkipExcluded,
+		"build":        SkipGitignore,
+		"notes.txt":    SkipNotIncluded,
+		"image.bin":    SkipBinary,
+		"main_test.go": SkipClass,
+	}
+	if len(skipped) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, skipped)
+	}
+	for path, reason := range expected {
+		if skipped[path] != reason {
+			t.Errorf("Expected %s to be skipped as %q, got %q", path, reason, skipped[path])
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	result := treeTestResult()
+	result.Files = append(result.Files, &FileInfo{RelativePath: "</script><b>x.go", TokenCount: 5, Language: "Go"})
+	result.recount()
+
+	report := NewReport(result, []SkippedFile{
+		{Path: "vendor", Reason: SkipExcluded},
+		{Path: "image.png", Reason: SkipBinary},
+	})
+	if report.Root != "project" || report.TotalTokens != 585 || report.TotalSize != 6544 {
+		t.Errorf("Unexpected totals: %s, %d tokens, %d bytes", report.Root, report.TotalTokens, report.TotalSize)
+	}
+	if len(report.Files) != 5 || report.Files[len(report.Files)-1].Path != "zz.go" {
+		t.Errorf("Expected the files sorted by path, got %+v", report.Files)
+	}
+	if report.Excluded[0].Path != "image.png" {
+		t.Errorf("Expected the excluded paths sorted, got %+v", report.Excluded)
+	}
+
+	var page strings.Builder
+	if err := WriteReport(&page, report); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	output := page.String()
+
+	if !strings.Contains(output, `"totalTokens":585`) {
+		t.Error("Expected the report data to be embedded in the page")
+	}
+	if strings.Contains(output, "</script><b>") {
+		t.Error("Expected file names to be escaped inside the script")
+	}
+	for _, external := range []string{"http://", "https://", "<link", "src="} {
+		if strings.Contains(output, external) {
+			t.Errorf("Expected a self-contained page, found %q", external)
+		}
+	}
+}
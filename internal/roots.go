@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScanRoots scans several directories and an explicit list of files into
+// a single result. Paths are made relative to the deepest directory
+// containing all of them, which becomes the result's root path. Roots nested
+// inside another root and files already found by a root are only included
+// once. Explicit files are not filtered by the include, exclude or
+// .gitignore rules.
+func (s *Scanner) ScanRoots(ctx context.Context, roots, files []string) (*ScanResult, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// Directories in the file list are scanned like roots
+	explicit := make([]string, 0, len(files))
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("file does not exist: %s", path)
+		}
+		if info.IsDir() {
+			roots = append(roots, path)
+		} else {
+			explicit = append(explicit, path)
+		}
+	}
+
+	absRoots := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("folder does not exist: %s", root)
+		}
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		absRoots = append(absRoots, abs)
+	}
+	absRoots = outermostRoots(absRoots)
+
+	absFiles := make([]string, 0, len(explicit))
+	for _, path := range explicit {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		absFiles = append(absFiles, abs)
+	}
+
+	// The common base is shown relative to the working directory unless an
+	// absolute path was given
+	base := commonBase(append(append([]string{}, absRoots...), parentDirs(absFiles)...))
+	rootPath := base
+	if !anyAbsolute(roots) && !anyAbsolute(explicit) {
+		if rel, err := filepath.Rel(cwd, base); err == nil {
+			rootPath = rel
+		}
+	}
+
+	result := &ScanResult{
+		RootPath:  rootPath,
+		Files:     make([]*FileInfo, 0),
+		NamesOnly: s.options.SkipContent,
+	}
+	seen := make(map[string]bool)
+
+	for _, root := range absRoots {
+		rel, _ := filepath.Rel(base, root)
+		result.Roots = append(result.Roots, rel)
+
+		scanned, err := s.ScanDirectoryContext(ctx, root)
+		for _, file := range scanned.Files {
+			if seen[file.Path] {
+				continue
+			}
+			seen[file.Path] = true
+			file.RelativePath, _ = filepath.Rel(base, file.Path)
+			result.Files = append(result.Files, file)
+		}
+		if err != nil {
+			result.Incomplete = scanned.Incomplete
+			result.recount()
+			return result, err
+		}
+	}
+
+	for _, path := range absFiles {
+		if err := ctx.Err(); err != nil {
+			result.Incomplete = true
+			result.recount()
+			return result, err
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		file, err := s.scanFile(path)
+		if err != nil || file == nil {
+			// Skip files that can't be read or processed, like a scan does
+			continue
+		}
+		file.RelativePath, _ = filepath.Rel(base, path)
+		result.Files = append(result.Files, file)
+	}
+
+	// A single section needs no separate trees
+	if len(result.Roots) == 1 && len(absFiles) == 0 {
+		result.Roots = nil
+	}
+
+	result.recount()
+	return result, nil
+}
+
+// scanFile reads a single file the way a scan would. It returns nil when
+// the file is skipped.
+func (s *Scanner) scanFile(path string) (*FileInfo, error) {
+	s.source = NewDirSource(filepath.Dir(path))
+
+	name := filepath.Base(path)
+	if s.options.Redact && IsSensitiveFile(name) {
+		return nil, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxFileSize {
+		return nil, nil
+	}
+
+	file := &FileInfo{
+		Path:         path,
+		RelativePath: name,
+		Size:         info.Size(),
+		ModTime:      info.ModTime(),
+	}
+	if s.options.SkipContent {
+		err = s.describeFile(file)
+	} else {
+		err = s.processFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.keepClass(file.Class) || !s.keepLanguage(file.Language) {
+		return nil, nil
+	}
+	if s.options.MaxTokens > 0 && file.TokenCount > s.options.MaxTokens {
+		return nil, nil
+	}
+	return file, nil
+}
+
+// recount recomputes the file and token totals
+func (r *ScanResult) recount() {
+	r.TotalFiles = 0
+	r.TotalTokens = 0
+	for _, file := range r.Files {
+		if !file.IsDirectory {
+			r.TotalFiles++
+			r.TotalTokens += file.TokenCount
+		}
+	}
+}
+
+// SplitRoots returns one result per root of a multi-root scan, with paths
+// relative to that root. Files outside every root, such as explicitly
+// listed files, are returned last in a section for the common base. A
+// single-root result is returned as is.
+func SplitRoots(result *ScanResult) []*ScanResult {
+	if len(result.Roots) == 0 {
+		return []*ScanResult{result}
+	}
+
+	sections := make([]*ScanResult, 0, len(result.Roots)+1)
+	claimed := make(map[*FileInfo]bool)
+
+	for _, root := range result.Roots {
+		section := &ScanResult{RootPath: filepath.Join(result.RootPath, root)}
+		for _, file := range result.Files {
+			rel, ok := relativeToRoot(root, file.RelativePath)
+			if !ok || claimed[file] {
+				continue
+			}
+			claimed[file] = true
+
+			sub := *file
+			sub.RelativePath = rel
+			section.Files = append(section.Files, &sub)
+		}
+		section.recount()
+		sections = append(sections, section)
+	}
+
+	rest := &ScanResult{RootPath: result.RootPath}
+	for _, file := range result.Files {
+		if !claimed[file] {
+			rest.Files = append(rest.Files, file)
+		}
+	}
+	if len(rest.Files) > 0 {
+		rest.recount()
+		sections = append(sections, rest)
+	}
+
+	return sections
+}
+
+// relativeToRoot returns path relative to root when it lies inside it
+func relativeToRoot(root, path string) (string, bool) {
+	if root == "." {
+		return path, true
+	}
+	if path == root {
+		return ".", true
+	}
+	prefix := root + string(filepath.Separator)
+	if strings.HasPrefix(path, prefix) {
+		return strings.TrimPrefix(path, prefix), true
+	}
+	return "", false
+}
+
+// outermostRoots removes duplicate roots and roots inside other roots
+func outermostRoots(roots []string) []string {
+	kept := make([]string, 0, len(roots))
+	for i, root := range roots {
+		covered := false
+		for j, other := range roots {
+			if i == j {
+				continue
+			}
+			// Keep the first of two identical roots
+			if other == root && j < i || other != root && isWithin(other, root) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, root)
+		}
+	}
+	return kept
+}
+
+// commonBase returns the deepest directory containing all absolute paths
+func commonBase(paths []string) string {
+	if len(paths) == 0 {
+		return "."
+	}
+
+	base := paths[0]
+	for _, path := range paths[1:] {
+		for !isWithin(base, path) {
+			parent := filepath.Dir(base)
+			if parent == base {
+				break
+			}
+			base = parent
+		}
+	}
+	return base
+}
+
+func parentDirs(paths []string) []string {
+	dirs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	return dirs
+}
+
+func anyAbsolute(paths []string) bool {
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFileList reads paths separated by newlines or, when the input
+// contains a NUL byte, by NULs as printed by git ls-files -z. Empty entries
+// are ignored.
+func ReadFileList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := byte('\n')
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = 0
+	}
+
+	paths := make([]string, 0)
+	for _, entry := range bytes.Split(data, []byte{sep}) {
+		path := string(entry)
+		if sep == '\n' {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanRoots(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"api/main.go":         "package api\n",
+		"shared/util/util.go": "package util\n",
+		"docs/guide.md":       "# Guide\n",
+		"README.md":           "# Project\n",
+	})
+	api := filepath.Join(tempDir, "api")
+	shared := filepath.Join(tempDir, "shared")
+
+	files := []string{
+		filepath.Join(tempDir, "README.md"),
+		filepath.Join(tempDir, "README.md"),
+		filepath.Join(tempDir, "docs", "guide.md"),
+		// Already covered by the api root
+		filepath.Join(api, "main.go"),
+	}
+	// The nested and repeated roots must not duplicate any file
+	roots := []string{api, shared, filepath.Join(shared, "util"), api}
+
+	result, err := NewScanner(&ScanOptions{}).ScanRoots(context.Background(), roots, files)
+	if err != nil {
+		t.Fatalf("ScanRoots failed: %v", err)
+	}
+
+	if result.RootPath != tempDir {
+		t.Errorf("Expected the common base %s as root, got %s", tempDir, result.RootPath)
+	}
+	if !reflect.DeepEqual(result.Roots, []string{"api", "shared"}) {
+		t.Errorf("Expected roots [api shared], got %v", result.Roots)
+	}
+
+	var paths []string
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			paths = append(paths, filepath.ToSlash(file.RelativePath))
+		}
+	}
+	expected := []string{"api/main.go", "shared/util/util.go", "README.md", "docs/guide.md"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected files %v, got %v", expected, paths)
+	}
+	if result.TotalFiles != 4 {
+		t.Errorf("Expected 4 files, got %d", result.TotalFiles)
+	}
+
+	// Every root gets its own tree, the listed files share one for the base
+	output := NewOutputFormatter(nil).FormatOutput(result)
+	for _, tree := range []string{"api\n└── main.go\n", "shared\n└── util\n", "└── README.md [docs]\n"} {
+		if !strings.Contains(output, tree) {
+			t.Errorf("Expected output to contain tree %q, got:\n%s", tree, output)
+		}
+	}
+	if strings.Count(output, "File: api/main.go") != 1 {
+		t.Errorf("Expected api/main.go exactly once, got:\n%s", output)
+	}
+}
+
+func TestScanRootsMissingPath(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewScanner(nil).ScanRoots(context.Background(), []string{missing}, nil); err == nil {
+		t.Error("Expected an error for a missing root")
+	}
+	if _, err := NewScanner(nil).ScanRoots(context.Background(), nil, []string{missing}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"newlines", "a.go\nsrc/b.go\n\n", []string{"a.go", "src/b.go"}},
+		{"crlf", "a.go\r\nb.go\r\n", []string{"a.go", "b.go"}},
+		{"nul", "a.go\x00name with\nnewline.go\x00", []string{"a.go", "name with\nnewline.go"}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := ReadFileList(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadFileList failed: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, paths)
+			}
+		})
+	}
+}
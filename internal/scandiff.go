@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File change statuses reported by CompareScans
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// FileChange is a file that differs between two scans
+type FileChange struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	Language  string `json:"language,omitempty"`
+	OldTokens int    `json:"oldTokens"`
+	NewTokens int    `json:"newTokens"`
+}
+
+// Delta is the change in tokens, negative when the file shrank
+func (c FileChange) Delta() int {
+	return c.NewTokens - c.OldTokens
+}
+
+// TokenDelta compares the tokens of a directory or language in two scans
+type TokenDelta struct {
+	Name      string `json:"name"`
+	OldTokens int    `json:"oldTokens"`
+	NewTokens int    `json:"newTokens"`
+}
+
+// Delta is the change in tokens, negative when it shrank
+func (d TokenDelta) Delta() int {
+	return d.NewTokens - d.OldTokens
+}
+
+// ThresholdCrossing is a file, or the total, that went over or back under a
+// limit between two scans
+type ThresholdCrossing struct {
+	// Path is empty for the total
+	Path      string `json:"path,omitempty"`
+	Limit     string `json:"limit"`
+	Value     int    `json:"value"`
+	OldTokens int    `json:"oldTokens"`
+	NewTokens int    `json:"newTokens"`
+}
+
+// ScanDiff is the token-level difference between two scans
+type ScanDiff struct {
+	OldFiles  int `json:"oldFiles"`
+	NewFiles  int `json:"newFiles"`
+	OldTokens int `json:"oldTokens"`
+	NewTokens int `json:"newTokens"`
+	// Files are the changed files, largest change first
+	Files []FileChange `json:"files"`
+	// Directories and Languages only list entries whose tokens changed
+	Directories []TokenDelta        `json:"directories"`
+	Languages   []TokenDelta        `json:"languages"`
+	Crossings   []ThresholdCrossing `json:"crossings,omitempty"`
+}
+
+// Count returns the number of changed files with status
+func (d *ScanDiff) Count(status string) int {
+	count := 0
+	for _, file := range d.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// DiffThresholds are the limits CompareScans checks, zero to skip one
+type DiffThresholds struct {
+	// MaxTokens is a per-file limit, as with --max-tokens
+	MaxTokens int
+	// Budget is a limit on the total, as with --budget
+	Budget int
+}
+
+// CompareScans reports the files, directories and languages whose tokens
+// changed between oldResult and newResult. Totals are recomputed from the
+// files, so dumps that include a prompt compare like plain scans.
+func CompareScans(oldResult, newResult *ScanResult, thresholds DiffThresholds) *ScanDiff {
+	oldFiles, newFiles := filesByPath(oldResult), filesByPath(newResult)
+	diff := &ScanDiff{
+		OldFiles:    len(oldFiles),
+		NewFiles:    len(newFiles),
+		Files:       make([]FileChange, 0),
+		Directories: make([]TokenDelta, 0),
+		Languages:   make([]TokenDelta, 0),
+	}
+
+	for path, file := range oldFiles {
+		diff.OldTokens += file.TokenCount
+		if _, ok := newFiles[path]; !ok {
+			diff.Files = append(diff.Files, FileChange{
+				Path: path, Status: ChangeRemoved, Language: file.Language, OldTokens: file.TokenCount,
+			})
+		}
+	}
+	for path, file := range newFiles {
+		diff.NewTokens += file.TokenCount
+		old, ok := oldFiles[path]
+		switch {
+		case !ok:
+			diff.Files = append(diff.Files, FileChange{
+				Path: path, Status: ChangeAdded, Language: file.Language, NewTokens: file.TokenCount,
+			})
+		case old.TokenCount != file.TokenCount || old.Content != file.Content:
+			diff.Files = append(diff.Files, FileChange{
+				Path: path, Status: ChangeModified, Language: file.Language,
+				OldTokens: old.TokenCount, NewTokens: file.TokenCount,
+			})
+		}
+	}
+	sort.Slice(diff.Files, func(i, j int) bool {
+		a, b := abs(diff.Files[i].Delta()), abs(diff.Files[j].Delta())
+		if a != b {
+			return a > b
+		}
+		return diff.Files[i].Path < diff.Files[j].Path
+	})
+
+	diff.Directories = compareTotals(directoryTokens(BuildTree(oldResult)), directoryTokens(BuildTree(newResult)))
+	diff.Languages = compareTotals(languageTokens(oldResult), languageTokens(newResult))
+	diff.Crossings = findCrossings(diff, oldFiles, newFiles, thresholds)
+	return diff
+}
+
+// filesByPath indexes the files of result by slash-separated path
+func filesByPath(result *ScanResult) map[string]*FileInfo {
+	files := make(map[string]*FileInfo)
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			files[filepath.ToSlash(file.RelativePath)] = file
+		}
+	}
+	return files
+}
+
+// directoryTokens returns the tokens of the files below every directory of
+// the tree, keyed by slash-separated path
+func directoryTokens(root *TreeNode) map[string]int {
+	totals := make(map[string]int)
+	var collect func(node *TreeNode, dir string)
+	collect = func(node *TreeNode, dir string) {
+		totals[dir] = node.TotalTokens
+		for _, child := range node.Children {
+			if child.IsDirectory {
+				collect(child, path.Join(dir, child.Name))
+			}
+		}
+	}
+	collect(root, ".")
+	return totals
+}
+
+func languageTokens(result *ScanResult) map[string]int {
+	totals := make(map[string]int)
+	for _, stat := range LanguageStats(result) {
+		totals[stat.Language] = stat.Tokens
+	}
+	return totals
+}
+
+// compareTotals lists the names whose totals differ, largest change first
+func compareTotals(oldTotals, newTotals map[string]int) []TokenDelta {
+	deltas := make([]TokenDelta, 0)
+	for name, tokens := range oldTotals {
+		if newTotals[name] != tokens {
+			deltas = append(deltas, TokenDelta{Name: name, OldTokens: tokens, NewTokens: newTotals[name]})
+		}
+	}
+	for name, tokens := range newTotals {
+		if _, ok := oldTotals[name]; !ok && tokens != 0 {
+			deltas = append(deltas, TokenDelta{Name: name, NewTokens: tokens})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		a, b := abs(deltas[i].Delta()), abs(deltas[j].Delta())
+		if a != b {
+			return a > b
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+	return deltas
+}
+
+func findCrossings(diff *ScanDiff, oldFiles, newFiles map[string]*FileInfo, thresholds DiffThresholds) []ThresholdCrossing {
+	crossed := func(oldTokens, newTokens, limit int) bool {
+		return limit > 0 && (oldTokens > limit) != (newTokens > limit)
+	}
+
+	var crossings []ThresholdCrossing
+	for _, change := range diff.Files {
+		if crossed(change.OldTokens, change.NewTokens, thresholds.MaxTokens) {
+			crossings = append(crossings, ThresholdCrossing{
+				Path: change.Path, Limit: "max-tokens", Value: thresholds.MaxTokens,
+				OldTokens: change.OldTokens, NewTokens: change.NewTokens,
+			})
+		}
+	}
+	if crossed(diff.OldTokens, diff.NewTokens, thresholds.Budget) {
+		crossings = append(crossings, ThresholdCrossing{
+			Limit: "budget", Value: thresholds.Budget, OldTokens: diff.OldTokens, NewTokens: diff.NewTokens,
+		})
+	}
+	return crossings
+}
+
+// ReadJSONScan loads a scan written with the json output format
+func ReadJSONScan(path string) (*ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("%s is not a code2txt JSON dump: %w", path, err)
+	}
+	return &result, nil
+}
+
+// FormatScanDiff renders diff as a plain text report
+func FormatScanDiff(diff *ScanDiff) string {
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("Tokens: %s -> %s (%s)\n",
+		formatNumber(diff.OldTokens), formatNumber(diff.NewTokens), formatDelta(diff.NewTokens-diff.OldTokens, diff.OldTokens)))
+	output.WriteString(fmt.Sprintf("Files: %d -> %d (%d added, %d removed, %d modified)\n",
+		diff.OldFiles, diff.NewFiles, diff.Count(ChangeAdded), diff.Count(ChangeRemoved), diff.Count(ChangeModified)))
+
+	if len(diff.Crossings) > 0 {
+		output.WriteString("\nThresholds crossed:\n")
+		for _, crossing := range diff.Crossings {
+			name := crossing.Path
+			if name == "" {
+				name = "total"
+			}
+			output.WriteString(fmt.Sprintf("  ! %s: %d -> %d tokens, --%s %d\n",
+				name, crossing.OldTokens, crossing.NewTokens, crossing.Limit, crossing.Value))
+		}
+	}
+
+	if len(diff.Files) > 0 {
+		output.WriteString("\nFiles:\n")
+		markers := map[string]string{ChangeAdded: "+", ChangeRemoved: "-", ChangeModified: "~"}
+		for _, file := range diff.Files {
+			output.WriteString(fmt.Sprintf("  %s %-50s %7d -> %-7d %+d\n",
+				markers[file.Status], file.Path, file.OldTokens, file.NewTokens, file.Delta()))
+		}
+	}
+
+	writeDeltas := func(title string, deltas []TokenDelta) {
+		if len(deltas) == 0 {
+			return
+		}
+		output.WriteString("\n" + title + ":\n")
+		for _, delta := range deltas {
+			output.WriteString(fmt.Sprintf("  %-52s %7d -> %-7d %+d\n",
+				delta.Name, delta.OldTokens, delta.NewTokens, delta.Delta()))
+		}
+	}
+	writeDeltas("Directories", diff.Directories)
+	writeDeltas("Languages", diff.Languages)
+
+	return output.String()
+}
+
+// formatDelta describes a change of delta from base, with a percentage
+func formatDelta(delta, base int) string {
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+	}
+	text := sign + formatNumber(abs(delta))
+	if base > 0 {
+		text += fmt.Sprintf(", %+.1f%%", float64(delta)*100/float64(base))
+	}
+	return text
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareScans(t *testing.T) {
+	oldResult := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Content: "a", TokenCount: 100, Language: "Go"},
+			{RelativePath: "lib", IsDirectory: true},
+			{RelativePath: filepath.Join("lib", "big.go"), Content: "b", TokenCount: 900, Language: "Go"},
+			{RelativePath: "old.md", Content: "c", TokenCount: 50, Language: "Markdown"},
+		},
+	}
+	newResult := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Content: "a", TokenCount: 100, Language: "Go"},
+			{RelativePath: "lib", IsDirectory: true},
+			{RelativePath: filepath.Join("lib", "big.go"), Content: "bb", TokenCount: 1200, Language: "Go"},
+			{RelativePath: "new.py", Content: "d", TokenCount: 30, Language: "Python"},
+		},
+	}
+
+	diff := CompareScans(oldResult, newResult, DiffThresholds{MaxTokens: 1000, Budget: 1200})
+	if diff.OldTokens != 1050 || diff.NewTokens != 1330 {
+		t.Errorf("Expected 1050 -> 1330 tokens, got %d -> %d", diff.OldTokens, diff.NewTokens)
+	}
+	if len(diff.Files) != 3 || diff.Files[0].Path != "lib/big.go" || diff.Files[0].Delta() != 300 {
+		t.Fatalf("Expected big.go to be the largest change, got %+v", diff.Files)
+	}
+	if diff.Count(ChangeAdded) != 1 || diff.Count(ChangeRemoved) != 1 || diff.Count(ChangeModified) != 1 {
+		t.Errorf("Unexpected change counts in %+v", diff.Files)
+	}
+
+	directories := make(map[string]int)
+	for _, delta := range diff.Directories {
+		directories[delta.Name] = delta.Delta()
+	}
+	if directories["lib"] != 300 || directories["."] != 280 || len(directories) != 2 {
+		t.Errorf("Unexpected directory deltas %+v", diff.Directories)
+	}
+	if len(diff.Languages) != 3 || diff.Languages[0].Name != "Go" || diff.Languages[0].Delta() != 300 {
+		t.Errorf("Unexpected language deltas %+v", diff.Languages)
+	}
+
+	if len(diff.Crossings) != 2 || diff.Crossings[0].Path != "lib/big.go" || diff.Crossings[1].Limit != "budget" {
+		t.Errorf("Expected big.go and the total to cross their limits, got %+v", diff.Crossings)
+	}
+
+	report := FormatScanDiff(diff)
+	for _, want := range []string{"1 added, 1 removed, 1 modified", "! lib/big.go: 900 -> 1200 tokens, --max-tokens 1000", "! total:"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected %q in the report:\n%s", want, report)
+		}
+	}
+}
+
+func TestReadJSONScan(t *testing.T) {
+	formatter := NewOutputFormatter(&OutputOptions{ShowTree: true, Format: "json"})
+	path := filepath.Join(t.TempDir(), "scan.json")
+	os.WriteFile(path, []byte(formatter.FormatOutput(formatTestResult())), 0644)
+
+	result, err := ReadJSONScan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := CompareScans(formatTestResult(), result, DiffThresholds{}); len(diff.Files) != 0 {
+		t.Errorf("Expected a JSON dump to compare equal to its scan, got %+v", diff.Files)
+	}
+
+	os.WriteFile(path, []byte("not json"), 0644)
+	if _, err := ReadJSONScan(path); err == nil {
+		t.Error("Expected an error for a file that is not a JSON dump")
+	}
+}
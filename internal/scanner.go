@@ -2,11 +2,15 @@ package internal
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -14,27 +18,127 @@ type ScanOptions struct {
 	IncludePatterns []string
 	ExcludePatterns []string
 	MaxTokens       int
+	// Progress, when set, is called as files are scanned
+	Progress ProgressFunc
+	// Skipped, when set, is called for every file or directory left out
+	// of the scan, with one of the Skip reasons. Excluded directories are
+	// reported once, not file by file.
+	Skipped func(relPath, reason string)
+	// ExcludePaths leaves out these exact files or directories, given as
+	// slash separated paths relative to the root
+	ExcludePaths map[string]bool
+	// Cache, when set, is used to skip re-reading unchanged files. It is
+	// not used with Redact.
+	Cache *Cache
+	// SkipSymlinks ignores symbolic links, which could point outside of
+	// the scanned directory
+	SkipSymlinks bool
+	// Redact skips credential files such as .env and private keys, and
+	// masks secrets found in the remaining files
+	Redact bool
+	// Only, when set, limits the scan to these files, given as slash
+	// separated paths relative to the root and mapped to their role
+	Only map[string]string
+	// SkipClasses drops files of these classes, see ClassifyFile
+	SkipClasses []string
+	// OnlyClasses, when set, keeps only files of these classes
+	OnlyClasses []string
+	// Languages, when set, keeps only files in these languages, given by
+	// name or alias, see LookupLanguage
+	Languages []string
+	// SkipContent lists files without reading them whole: Content and
+	// TokenCount stay empty, MaxTokens has no effect, languages are detected
+	// from the file name alone, and classes and binary files from the name
+	// and the start of the file
+	SkipContent bool
 }
 
 type FileInfo struct {
-	Path         string
-	RelativePath string
-	Size         int64
-	TokenCount   int
-	Content      string
-	IsDirectory  bool
+	Path         string    `json:"-"`
+	RelativePath string    `json:"path"`
+	Size         int64     `json:"size"`
+	ModTime      time.Time `json:"-"`
+	TokenCount   int       `json:"tokens"`
+	Content      string    `json:"content,omitempty"`
+	IsDirectory  bool      `json:"isDirectory,omitempty"`
+	// Role is set for files selected through ScanOptions.Only
+	Role string `json:"role,omitempty"`
+	// Class tells source files apart from tests, generated code and
+	// others, see ClassifyFile
+	Class string `json:"class,omitempty"`
+	// Language is the detected language name, empty when unknown
+	Language string `json:"language,omitempty"`
+	// StartLine is the number of the first line of Content when it is one
+	// part of a file split across chunks; zero means the file starts at 1
+	StartLine int `json:"startLine,omitempty"`
+
+	// original is the file before line numbers were added, see
+	// numberedResult
+	original *FileInfo
 }
 
 type ScanResult struct {
-	RootPath    string
-	Files       []*FileInfo
-	TotalTokens int
-	TotalFiles  int
+	RootPath    string      `json:"root"`
+	Files       []*FileInfo `json:"files"`
+	TotalTokens int         `json:"totalTokens"`
+	TotalFiles  int         `json:"totalFiles"`
+	// Incomplete is set when the scan was cancelled before it finished
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Roots lists the scanned directories relative to RootPath when several
+	// were combined, see ScanRoots
+	Roots []string `json:"roots,omitempty"`
+	// Part and Parts number the parts of a split dump, see
+	// OutputFormatter.Split; both are zero for a whole dump
+	Part  int `json:"part,omitempty"`
+	Parts int `json:"parts,omitempty"`
+	// NamesOnly is set when the files were listed with
+	// ScanOptions.SkipContent, so there are no token counts to report
+	NamesOnly bool `json:"-"`
+
+	// numbered is set on the copy made by OutputFormatter.Prepare for
+	// line-numbered output, so the numbers are not added twice
+	numbered bool
 }
 
+// Reasons passed to ScanOptions.Skipped
+const (
+	SkipExcluded    = "excluded"
+	SkipGitignore   = "gitignore"
+	SkipNotIncluded = "not included"
+	SkipSymlink     = "symlink"
+	SkipCredentials = "credentials"
+	SkipTooLarge    = "too large"
+	SkipBinary      = "binary"
+	SkipUnreadable  = "unreadable"
+	SkipClass       = "class"
+	SkipLanguage    = "language"
+	SkipMaxTokens   = "max tokens"
+)
+
+// maxFileSize is the size above which files are skipped (10MB)
+const maxFileSize = 10 * 1024 * 1024
+
 type Scanner struct {
 	options           *ScanOptions
 	gitignorePatterns []string
+	// source is the source of the last scan
+	source *Source
+	// onlyDirs holds the parent directories of the files in options.Only
+	onlyDirs map[string]bool
+}
+
+// DefaultExcludePatterns returns the patterns a scanner skips when no
+// exclude patterns are given
+func DefaultExcludePatterns() []string {
+	return []string{
+		"*.exe", "*.dll", "*.so", "*.dylib",
+		"*.jpg", "*.jpeg", "*.png", "*.gif", "*.bmp",
+		"*.mp3", "*.mp4", "*.avi", "*.mov",
+		"*.zip", "*.tar", "*.gz", "*.rar",
+		"node_modules", ".git", ".svn", ".hg",
+		"*.log", "*.tmp", "*.cache",
+		".DS_Store", "Thumbs.db",
+	}
 }
 
 func NewScanner(options *ScanOptions) *Scanner {
@@ -44,15 +148,7 @@ func NewScanner(options *ScanOptions) *Scanner {
 
 	// Default exclude patterns
 	if len(options.ExcludePatterns) == 0 {
-		options.ExcludePatterns = []string{
-			"*.exe", "*.dll", "*.so", "*.dylib",
-			"*.jpg", "*.jpeg", "*.png", "*.gif", "*.bmp",
-			"*.mp3", "*.mp4", "*.avi", "*.mov",
-			"*.zip", "*.tar", "*.gz", "*.rar",
-			"node_modules", ".git", ".svn", ".hg",
-			"*.log", "*.tmp", "*.cache",
-			".DS_Store", "Thumbs.db",
-		}
+		options.ExcludePatterns = DefaultExcludePatterns()
 	}
 
 	return &Scanner{
@@ -61,41 +157,90 @@ func NewScanner(options *ScanOptions) *Scanner {
 }
 
 func (s *Scanner) ScanDirectory(rootPath string) (*ScanResult, error) {
+	return s.ScanDirectoryContext(context.Background(), rootPath)
+}
+
+// ScanDirectoryContext scans rootPath like ScanDirectory and stops early
+// with the context's error once ctx is cancelled. The files gathered so far
+// are still returned, with Incomplete set on the result.
+func (s *Scanner) ScanDirectoryContext(ctx context.Context, rootPath string) (*ScanResult, error) {
+	return s.ScanSource(ctx, NewDirSource(rootPath))
+}
+
+// ScanFS scans the files in fsys, such as an fstest.MapFS or an overlay.
+// rootName is reported as the result's root path.
+func (s *Scanner) ScanFS(ctx context.Context, fsys fs.FS, rootName string) (*ScanResult, error) {
+	return s.ScanSource(ctx, NewFSSource(rootName, fsys))
+}
+
+// ScanSource scans src. The cache is only used for directories on disk,
+// since other sources have no reliable modification times.
+func (s *Scanner) ScanSource(ctx context.Context, src *Source) (*ScanResult, error) {
 	result := &ScanResult{
-		RootPath: rootPath,
-		Files:    make([]*FileInfo, 0),
+		RootPath:  src.Name,
+		Files:     make([]*FileInfo, 0),
+		NamesOnly: s.options.SkipContent,
 	}
 
+	s.source = src
+	s.loadOnlyDirs()
+
 	// Load .gitignore if it exists
-	s.loadGitignore(rootPath)
+	s.loadGitignore()
+
+	// Counting first lets progress show a bar; listing the directories is
+	// cheap next to reading every file
+	progress := ScanProgress{}
+	if s.options.Progress != nil {
+		if total, err := s.countFiles(ctx, "."); err == nil {
+			progress.TotalFiles = total
+		}
+	}
+	err := s.walk(ctx, ".", result, &progress)
+
+	if s.options.Progress != nil {
+		progress.Done = true
+		s.options.Progress(progress)
+	}
+
+	// Only a walk cut short is incomplete, not one that finished just before
+	// the deadline
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		result.Incomplete = true
+	}
+
+	return result, err
+}
 
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+// walk scans start, a slash-separated path inside the scanned filesystem,
+// and appends the files it finds to result
+func (s *Scanner) walk(ctx context.Context, start string, result *ScanResult, progress *ScanProgress) error {
+	return fs.WalkDir(s.source.FS, start, func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		relPath, _ := filepath.Rel(rootPath, path)
+		relPath := filepath.FromSlash(name)
 
-		// Skip if matches exclude patterns
-		if s.shouldExclude(relPath, d.IsDir()) {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+		keep, reason, err := s.filterEntry(name, d)
+		if reason != "" {
+			s.skip(relPath, reason)
 		}
-
-		// Skip if doesn't match include patterns (when specified)
-		if len(s.options.IncludePatterns) > 0 && !d.IsDir() {
-			if !s.shouldInclude(relPath) {
-				return nil
-			}
+		if !keep {
+			return err
 		}
 
 		fileInfo := &FileInfo{
-			Path:         path,
+			Path:         filepath.Join(s.source.Name, relPath),
 			RelativePath: relPath,
 			IsDirectory:  d.IsDir(),
 		}
+		if !d.IsDir() && s.options.Only != nil {
+			fileInfo.Role = s.options.Only[name]
+		}
 
 		if !d.IsDir() {
 			info, err := d.Info()
@@ -104,20 +249,43 @@ func (s *Scanner) ScanDirectory(rootPath string) (*ScanResult, error) {
 			}
 
 			fileInfo.Size = info.Size()
+			fileInfo.ModTime = info.ModTime()
 
-			// Skip large files (over 10MB)
-			if fileInfo.Size > 10*1024*1024 {
+			// Skip large files
+			if fileInfo.Size > maxFileSize {
+				s.skip(relPath, SkipTooLarge)
 				return nil
 			}
 
 			// Read and process file content
-			if err := s.processFile(fileInfo); err != nil {
+			if s.options.SkipContent {
+				err = s.describeFile(fileInfo)
+			} else {
+				err = s.processFile(fileInfo)
+			}
+			s.reportProgress(progress, fileInfo, err == nil)
+			if err != nil {
 				// Skip files that can't be read or processed
+				if err == errBinaryFile {
+					s.skip(relPath, SkipBinary)
+				} else {
+					s.skip(relPath, SkipUnreadable)
+				}
+				return nil
+			}
+
+			if !s.keepClass(fileInfo.Class) {
+				s.skip(relPath, SkipClass)
+				return nil
+			}
+			if !s.keepLanguage(fileInfo.Language) {
+				s.skip(relPath, SkipLanguage)
 				return nil
 			}
 
 			// Skip if over max tokens limit
 			if s.options.MaxTokens > 0 && fileInfo.TokenCount > s.options.MaxTokens {
+				s.skip(relPath, SkipMaxTokens)
 				return nil
 			}
 
@@ -128,65 +296,402 @@ func (s *Scanner) ScanDirectory(rootPath string) (*ScanResult, error) {
 		result.Files = append(result.Files, fileInfo)
 		return nil
 	})
+}
 
-	return result, err
+// filterEntry decides whether the walk keeps the entry name, before it is
+// read. A left out entry comes with the reason to report, empty when it is
+// left out silently, and fs.SkipDir for directories.
+func (s *Scanner) filterEntry(name string, d fs.DirEntry) (bool, string, error) {
+	relPath := filepath.FromSlash(name)
+
+	if s.options.Only != nil && name != "." {
+		if d.IsDir() && !s.onlyDirs[name] {
+			return false, "", fs.SkipDir
+		}
+		if _, ok := s.options.Only[name]; !ok && !d.IsDir() {
+			return false, "", nil
+		}
+	}
+
+	// Skip if matches exclude patterns
+	if reason := s.excludeReason(relPath); reason != "" {
+		if d.IsDir() {
+			return false, reason, fs.SkipDir
+		}
+		return false, reason, nil
+	}
+
+	if s.options.SkipSymlinks && d.Type()&fs.ModeSymlink != 0 {
+		return false, SkipSymlink, nil
+	}
+	if s.options.Redact && !d.IsDir() && IsSensitiveFile(relPath) {
+		return false, SkipCredentials, nil
+	}
+
+	// Skip if doesn't match include patterns (when specified)
+	if len(s.options.IncludePatterns) > 0 && !d.IsDir() && !s.shouldInclude(relPath) {
+		return false, SkipNotIncluded, nil
+	}
+	return true, "", nil
+}
+
+// countFiles counts the files below start that the walk will read, without
+// reading them, so progress can be shown against a known total
+func (s *Scanner) countFiles(ctx context.Context, start string) (int, error) {
+	count := 0
+	err := fs.WalkDir(s.source.FS, start, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		keep, _, err := s.filterEntry(name, d)
+		if !keep {
+			return err
+		}
+		if !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// RescanPaths updates a result produced by the scanner's last scan after
+// the given paths changed. Deleted paths are dropped, and changed files or
+// directories are scanned again, so only the affected files are re-read.
+// A change to the root or to its .gitignore rescans everything with the
+// rules read again.
+func (s *Scanner) RescanPaths(ctx context.Context, result *ScanResult, paths []string) error {
+	if s.source == nil || s.source.Name != result.RootPath {
+		s.source = NewDirSource(result.RootPath)
+	}
+	s.loadOnlyDirs()
+
+	for _, path := range paths {
+		relPath, err := filepath.Rel(result.RootPath, path)
+		if err == nil && (relPath == "." || relPath == ".gitignore") {
+			s.loadGitignore()
+			result.Files = result.Files[:0]
+			progress := ScanProgress{}
+			if err := s.walk(ctx, ".", result, &progress); err != nil {
+				return err
+			}
+			result.recount()
+			return nil
+		}
+	}
+
+	for _, path := range paths {
+		relPath, err := filepath.Rel(result.RootPath, path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+
+		// Drop the old entries for the path and everything below it
+		prefix := relPath + string(filepath.Separator)
+		files := result.Files[:0]
+		for _, file := range result.Files {
+			if file.RelativePath != relPath && !strings.HasPrefix(file.RelativePath, prefix) {
+				files = append(files, file)
+			}
+		}
+		result.Files = files
+
+		if s.Excludes(relPath) {
+			continue
+		}
+		name := filepath.ToSlash(relPath)
+		if _, err := fs.Stat(s.source.FS, name); err != nil {
+			// Removed, nothing to scan again
+			continue
+		}
+
+		progress := ScanProgress{}
+		if err := s.walk(ctx, name, result, &progress); err != nil {
+			return err
+		}
+	}
+
+	// Recompute the totals from scratch
+	result.recount()
+	return nil
+}
+
+// Excludes reports whether relPath, relative to the scanned root, is
+// skipped by the exclude or .gitignore rules, either itself or through one
+// of its parent directories
+func (s *Scanner) Excludes(relPath string) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := range parts {
+		if s.shouldExclude(filepath.Join(parts[:i+1]...), i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRules reads the .gitignore of src, so Excludes can answer for its
+// files without scanning it first
+func (s *Scanner) LoadRules(src *Source) {
+	s.source = src
+	s.loadGitignore()
+}
+
+// loadOnlyDirs collects the directories that lead to the files in
+// options.Only, so the walk can skip every other directory
+func (s *Scanner) loadOnlyDirs() {
+	s.onlyDirs = nil
+	if s.options.Only == nil {
+		return
+	}
+
+	s.onlyDirs = make(map[string]bool)
+	for name := range s.options.Only {
+		for dir := path.Dir(name); dir != "." && !s.onlyDirs[dir]; dir = path.Dir(dir) {
+			s.onlyDirs[dir] = true
+		}
+	}
 }
 
+// skip reports a file or directory left out of the scan
+func (s *Scanner) skip(relPath, reason string) {
+	if s.options.Skipped != nil {
+		s.options.Skipped(relPath, reason)
+	}
+}
+
+func (s *Scanner) reportProgress(progress *ScanProgress, fileInfo *FileInfo, processed bool) {
+	if s.options.Progress == nil {
+		return
+	}
+
+	progress.FilesScanned++
+	progress.CurrentPath = fileInfo.RelativePath
+	if processed {
+		progress.BytesRead += fileInfo.Size
+		progress.TokensCounted += fileInfo.TokenCount
+	}
+	s.options.Progress(*progress)
+}
+
+var errBinaryFile = fmt.Errorf("binary file")
+
 func (s *Scanner) processFile(fileInfo *FileInfo) error {
-	content, err := os.ReadFile(fileInfo.Path)
+	if err := s.loadFile(fileInfo); err != nil {
+		return err
+	}
+
+	fileInfo.Class = ClassifyFile(fileInfo.RelativePath, fileInfo.Content)
+	if lang := DetectLanguage(fileInfo.RelativePath, fileInfo.Content); lang != nil {
+		fileInfo.Language = lang.Name
+	}
+
+	// Mask credentials before anything else sees the content
+	if s.options.Redact {
+		if redacted, count := RedactSecrets(fileInfo.Content); count > 0 {
+			fileInfo.Content = redacted
+			fileInfo.TokenCount = CountTokens(redacted)
+		}
+	}
+
+	return nil
+}
+
+// describeFile classifies a file from its name and the start of its content
+// and detects its language from its name, for scans that do not read content.
+// Binary files are told apart by their first binarySniffSize bytes.
+func (s *Scanner) describeFile(fileInfo *FileInfo) error {
+	head, err := readHead(s.source.FS, filepath.ToSlash(fileInfo.RelativePath), classifyHeadSize)
+	if err != nil {
+		return err
+	}
+
+	// Drop binary files as a full scan would, judging from the first bytes
+	sniff := head
+	if len(sniff) > binarySniffSize {
+		sniff = trimPartialRune(sniff[:binarySniffSize])
+	}
+	if isBinary(sniff, fileInfo.Path) {
+		return errBinaryFile
+	}
+
+	fileInfo.Class = ClassifyFile(fileInfo.RelativePath, string(head))
+	if lang := languageByName(fileInfo.RelativePath); lang != nil {
+		fileInfo.Language = lang.Name
+	}
+	return nil
+}
+
+// binarySniffSize is how much of a file listings look at to tell binary files
+const binarySniffSize = 512
+
+// isBinary reports whether content of the file at path is binary. Files in a
+// known language are text even when they are not valid UTF-8.
+func isBinary(content []byte, path string) bool {
+	return !utf8.Valid(content) && languageByName(path) == nil
+}
+
+// trimPartialRune drops a UTF-8 sequence cut short at the end of b
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// readHead reads at most size bytes from the start of a file
+func readHead(fsys fs.FS, name string, size int) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, size)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// loadFile reads the content of a file and counts its tokens, reusing the
+// cache when possible. Redacting scans bypass the cache, which would
+// otherwise keep the secrets in plain text.
+func (s *Scanner) loadFile(fileInfo *FileInfo) error {
+	var cache *Cache
+	if s.source.OnDisk && !s.options.Redact {
+		cache = s.options.Cache
+	}
+	var cached *CacheEntry
+	if cache != nil {
+		cached, _ = cache.Get(fileInfo.Path)
+		if cached != nil && cached.Tokenizer != TokenizerID {
+			cached = nil
+		}
+
+		// Unchanged since the last run, skip reading entirely
+		if cached != nil && cached.Size == fileInfo.Size && cached.ModTime.Equal(fileInfo.ModTime) {
+			if cached.Binary {
+				return errBinaryFile
+			}
+			fileInfo.Content = cached.Content
+			fileInfo.TokenCount = cached.TokenCount
+			return nil
+		}
+	}
+
+	content, err := fs.ReadFile(s.source.FS, filepath.ToSlash(fileInfo.RelativePath))
 	if err != nil {
 		return err
 	}
 
-	// Check if file is binary
-	if !utf8.Valid(content) && !s.isTextFile(fileInfo.Path) {
-		return fmt.Errorf("binary file")
+	entry := &CacheEntry{
+		Path:      fileInfo.Path,
+		Size:      fileInfo.Size,
+		ModTime:   fileInfo.ModTime,
+		Tokenizer: TokenizerID,
+	}
+	if cache != nil {
+		entry.Hash = HashContent(content)
+	}
+
+	if isBinary(content, fileInfo.Path) {
+		if cache != nil {
+			entry.Binary = true
+			cache.Put(entry)
+		}
+		return errBinaryFile
 	}
 
 	fileInfo.Content = string(content)
-	fileInfo.TokenCount = CountTokens(fileInfo.Content)
+
+	// Only the timestamp changed, the token count is still valid
+	if cached != nil && !cached.Binary && cached.Hash == entry.Hash {
+		fileInfo.TokenCount = cached.TokenCount
+	} else {
+		fileInfo.TokenCount = CountTokens(fileInfo.Content)
+	}
+
+	if cache != nil {
+		entry.Content = fileInfo.Content
+		entry.TokenCount = fileInfo.TokenCount
+		// A failed write only costs a cache miss next time
+		cache.Put(entry)
+	}
 
 	return nil
 }
 
-func (s *Scanner) isTextFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	textExtensions := []string{
-		".txt", ".md", ".rst", ".json", ".xml", ".yaml", ".yml",
-		".go", ".py", ".js", ".ts", ".java", ".c", ".cpp", ".h", ".hpp",
-		".cs", ".php", ".rb", ".swift", ".kt", ".rs", ".sh", ".bat",
-		".html", ".css", ".scss", ".less", ".sql", ".r", ".m",
+// keepClass applies the SkipClasses and OnlyClasses options
+func (s *Scanner) keepClass(class string) bool {
+	for _, skipped := range s.options.SkipClasses {
+		if class == skipped {
+			return false
+		}
 	}
-
-	for _, textExt := range textExtensions {
-		if ext == textExt {
+	if len(s.options.OnlyClasses) == 0 {
+		return true
+	}
+	for _, only := range s.options.OnlyClasses {
+		if class == only {
 			return true
 		}
 	}
+	return false
+}
 
+// keepLanguage applies the Languages option
+func (s *Scanner) keepLanguage(language string) bool {
+	if len(s.options.Languages) == 0 {
+		return true
+	}
+	for _, name := range s.options.Languages {
+		if lang, ok := LookupLanguage(name); ok && lang.Name == language {
+			return true
+		}
+	}
 	return false
 }
 
 func (s *Scanner) shouldExclude(path string, isDir bool) bool {
+	return s.excludeReason(path) != ""
+}
+
+// excludeReason returns SkipGitignore or SkipExcluded when path matches a
+// .gitignore or exclude pattern or is one of the excluded paths, and ""
+// otherwise
+func (s *Scanner) excludeReason(path string) string {
+	if s.options.ExcludePaths[filepath.ToSlash(path)] {
+		return SkipExcluded
+	}
+
 	// Check gitignore patterns
 	for _, pattern := range s.gitignorePatterns {
 		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
+			return SkipGitignore
 		}
 	}
 
 	// Check exclude patterns
 	for _, pattern := range s.options.ExcludePatterns {
 		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
+			return SkipExcluded
 		}
 		// Also check full path for directory patterns
 		if strings.Contains(path, pattern) {
-			return true
+			return SkipExcluded
 		}
 	}
 
-	return false
+	return ""
 }
 
 func (s *Scanner) shouldInclude(path string) bool {
@@ -198,9 +703,10 @@ func (s *Scanner) shouldInclude(path string) bool {
 	return false
 }
 
-func (s *Scanner) loadGitignore(rootPath string) {
-	gitignorePath := filepath.Join(rootPath, ".gitignore")
-	file, err := os.Open(gitignorePath)
+func (s *Scanner) loadGitignore() {
+	s.gitignorePatterns = nil
+
+	file, err := s.source.FS.Open(".gitignore")
 	if err != nil {
 		return
 	}
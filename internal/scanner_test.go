@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestNewScanner(t *testing.T) {
@@ -30,12 +34,7 @@ func TestNewScanner(t *testing.T) {
 	}
 }
 
-func TestScanDirectory(t *testing.T) {
-	// Create a temporary directory with test files
-	tempDir := t.TempDir()
-
-	// Create test files
-	goFile := filepath.Join(tempDir, "main.go")
+func TestScanFS(t *testing.T) {
 	goContent := `package main
 
 import "fmt"
@@ -44,39 +43,71 @@ func main() {
 	fmt.Println("Hello, World!")
 }
 `
+	fsys := fstest.MapFS{
+		"main.go":              {Data: []byte(goContent)},
+		"readme.txt":           {Data: []byte("This is a test file.")},
+		"src/utils.go":         {Data: []byte("package src\n")},
+		"debug.log":            {Data: []byte("excluded by default\n")},
+		"node_modules/x/a.js":  {Data: []byte("excluded by default\n")},
+		"image.bin":            {Data: []byte{0xff, 0xfe, 0x00, 0x01}},
+		"generated/output.txt": {Data: []byte("ignored by .gitignore\n")},
+		".gitignore":           {Data: []byte("generated\n")},
+	}
 
-	err := os.WriteFile(goFile, []byte(goContent), 0644)
+	scanner := NewScanner(&ScanOptions{})
+	result, err := scanner.ScanFS(context.Background(), fsys, "project")
 	if err != nil {
-		t.Fatalf("Failed to create test Go file: %v", err)
+		t.Fatalf("Failed to scan: %v", err)
 	}
 
-	txtFile := filepath.Join(tempDir, "readme.txt")
-	txtContent := "This is a test file."
-
-	err = os.WriteFile(txtFile, []byte(txtContent), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test txt file: %v", err)
+	if result.RootPath != "project" {
+		t.Errorf("Expected root path project, got %s", result.RootPath)
 	}
 
-	// Create scanner and scan directory
-	scanner := NewScanner(&ScanOptions{})
-	result, err := scanner.ScanDirectory(tempDir)
+	contents := make(map[string]string)
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			contents[filepath.ToSlash(file.RelativePath)] = file.Content
+		}
+	}
 
-	if err != nil {
-		t.Fatalf("Failed to scan directory: %v", err)
+	expected := []string{"main.go", "readme.txt", "src/utils.go"}
+	if len(contents) != len(expected) {
+		t.Errorf("Expected files %v, got %v", expected, contents)
+	}
+	for _, name := range expected {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("Expected %s to be scanned", name)
+		}
+	}
+	if contents["main.go"] != goContent {
+		t.Errorf("Unexpected content for main.go: %q", contents["main.go"])
+	}
+	if result.TotalFiles != len(expected) {
+		t.Errorf("Expected %d files, got %d", len(expected), result.TotalFiles)
 	}
+}
 
-	if result == nil {
-		t.Fatal("Expected scan result, got nil")
+func TestScanDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
 	}
 
-	if result.TotalFiles == 0 {
-		t.Error("Expected files to be found")
+	result, err := NewScanner(&ScanOptions{}).ScanDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
 	}
 
+	if result.TotalFiles != 1 {
+		t.Errorf("Expected 1 file, got %d", result.TotalFiles)
+	}
 	if result.RootPath != tempDir {
 		t.Errorf("Expected root path %s, got %s", tempDir, result.RootPath)
 	}
+	if result.Files[1].Path != filepath.Join(tempDir, "main.go") {
+		t.Errorf("Expected the file path on disk, got %s", result.Files[1].Path)
+	}
 }
 
 func TestShouldExclude(t *testing.T) {
@@ -127,3 +158,102 @@ func TestShouldInclude(t *testing.T) {
 		}
 	}
 }
+
+func TestScanContextCancelled(t *testing.T) {
+	fsys := fstest.MapFS{"main.go": {Data: []byte("package main\n")}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := NewScanner(nil).ScanFS(ctx, fsys, "project")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if result == nil || !result.Incomplete {
+		t.Fatal("Expected an incomplete result")
+	}
+
+	output := NewOutputFormatter(nil).FormatOutput(result)
+	if !strings.HasPrefix(output, "WARNING: INCOMPLETE OUTPUT") {
+		t.Errorf("Expected incomplete marker at the top, got %q", output)
+	}
+}
+
+func TestScanCancelledAfterWalk(t *testing.T) {
+	fsys := fstest.MapFS{"main.go": {Data: []byte("package main\n")}}
+
+	// Cancel once the walk is over, before the scan returns
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options := &ScanOptions{Progress: func(progress ScanProgress) {
+		if progress.Done {
+			cancel()
+		}
+	}}
+
+	result, err := NewScanner(options).ScanFS(ctx, fsys, "project")
+	if err != nil {
+		t.Fatalf("Expected the finished walk to succeed, got %v", err)
+	}
+	if result.Incomplete || result.TotalFiles != 1 {
+		t.Errorf("Expected a complete result, got incomplete=%t with %d files", result.Incomplete, result.TotalFiles)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	if err := WriteFileAtomic(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("Expected replaced content, got %q (%v)", data, err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestScanSkipContent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":      {Data: []byte("package main\n")},
+		"main_test.go": {Data: []byte("package main\n")},
+		"mock.go":      {Data: []byte("// Code generated by MockGen. DO NOT EDIT.\npackage main\n")},
+		"blob.dat":     {Data: append([]byte{0xff, 0xfe, 0x00, 0x01}, make([]byte, 1024)...)},
+		"notes.txt":    {Data: []byte(strings.Repeat("a", binarySniffSize-1) + "é" + strings.Repeat("\n", 100))},
+	}
+
+	result, err := NewScanner(&ScanOptions{SkipContent: true}).ScanFS(context.Background(), fsys, "project")
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	// Binary files are left out as in a full scan
+	if result.TotalFiles != 4 || result.TotalTokens != 0 {
+		t.Errorf("Expected 4 files without tokens, got %d files and %d tokens", result.TotalFiles, result.TotalTokens)
+	}
+	for _, file := range result.Files {
+		if file.Content != "" {
+			t.Errorf("Expected %s not to be read", file.RelativePath)
+		}
+		if file.RelativePath == "main_test.go" && (file.Class != ClassTest || file.Language != "Go") {
+			t.Errorf("Expected a Go test from the name, got %s %s", file.Language, file.Class)
+		}
+		// The class matches the one a full scan gives
+		if file.RelativePath == "mock.go" && file.Class != ClassGenerated {
+			t.Errorf("Expected mock.go to be generated from its header, got %s", file.Class)
+		}
+	}
+
+	summary := NewOutputFormatter(nil).FormatSummary(result)
+	if !strings.Contains(summary, "Files: 4") || strings.Contains(summary, "Tokens") {
+		t.Errorf("Expected a summary without tokens, got:\n%s", summary)
+	}
+}
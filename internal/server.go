@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServerOptions configures the HTTP API
+type ServerOptions struct {
+	// Roots maps the names clients use to the directories they may scan.
+	// Nothing outside these directories is ever read.
+	Roots map[string]string
+	// Cache, when set, is shared by all scans
+	Cache *Cache
+	// Redact skips credential files and masks secrets in every response
+	Redact bool
+}
+
+// Server exposes scans of allowlisted directories over HTTP
+type Server struct {
+	roots  map[string]string
+	names  []string
+	cache  *Cache
+	redact bool
+	mux    *http.ServeMux
+}
+
+// httpError is an error carrying the HTTP status to respond with
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func newHTTPError(status int, format string, args ...interface{}) error {
+	return &httpError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// NewServer creates a server for the given roots. Every root must be an
+// existing directory.
+func NewServer(options *ServerOptions) (*Server, error) {
+	if options == nil || len(options.Roots) == 0 {
+		return nil, fmt.Errorf("at least one root directory is required")
+	}
+
+	s := &Server{
+		roots:  make(map[string]string),
+		cache:  options.Cache,
+		redact: options.Redact,
+		mux:    http.NewServeMux(),
+	}
+
+	for name, dir := range options.Roots {
+		// Resolve symlinks up front so confinement checks compare real paths
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		real, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", name, err)
+		}
+		if info, err := os.Stat(real); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("root %s is not a directory: %s", name, dir)
+		}
+		s.roots[name] = real
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+
+	s.mux.HandleFunc("/api/roots", s.handleRoots)
+	s.mux.HandleFunc("/api/scan", s.handleScan)
+	s.mux.HandleFunc("/api/dump", s.handleDump)
+	s.mux.HandleFunc("/api/tree", s.handleTree)
+	s.mux.HandleFunc("/api/stats", s.handleStats)
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, newHTTPError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// resolveTarget maps the root and path query parameters to a directory
+// inside one of the allowlisted roots
+func (s *Server) resolveTarget(query url.Values) (string, error) {
+	name := query.Get("root")
+	if name == "" {
+		if len(s.names) != 1 {
+			return "", newHTTPError(http.StatusBadRequest, "root parameter is required (available: %s)",
+				strings.Join(s.names, ", "))
+		}
+		name = s.names[0]
+	}
+
+	root, ok := s.roots[name]
+	if !ok {
+		return "", newHTTPError(http.StatusNotFound, "unknown root %q", name)
+	}
+
+	target := root
+	if rel := query.Get("path"); rel != "" {
+		if filepath.IsAbs(rel) || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, `\`) {
+			return "", newHTTPError(http.StatusBadRequest, "path must be relative to the root")
+		}
+		clean := filepath.Clean(filepath.FromSlash(rel))
+		if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return "", newHTTPError(http.StatusForbidden, "path escapes the root")
+		}
+		target = filepath.Join(root, clean)
+	}
+
+	// A symlink inside the root may still point outside of it
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", newHTTPError(http.StatusNotFound, "path not found")
+	}
+	if !isWithin(root, real) {
+		return "", newHTTPError(http.StatusForbidden, "path escapes the root")
+	}
+	if info, err := os.Stat(real); err != nil || !info.IsDir() {
+		return "", newHTTPError(http.StatusBadRequest, "path is not a directory")
+	}
+
+	return real, nil
+}
+
+// isWithin reports whether path is root or inside it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// scanRequest scans the directory selected by the request's query string
+func (s *Server) scanRequest(r *http.Request) (*ScanResult, []*FileInfo, error) {
+	query := r.URL.Query()
+
+	target, err := s.resolveTarget(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxTokens, err := intParam(query, "max_tokens")
+	if err != nil {
+		return nil, nil, err
+	}
+	budget, err := intParam(query, "budget")
+	if err != nil {
+		return nil, nil, err
+	}
+	languages := listParam(query, "lang")
+	if err := ValidateLanguages(languages); err != nil {
+		return nil, nil, newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+
+	// Client patterns narrow the scan further, they never lift the
+	// defaults that keep .git and node_modules out
+	scanner := NewScanner(&ScanOptions{
+		IncludePatterns: listParam(query, "include"),
+		ExcludePatterns: append(DefaultExcludePatterns(), listParam(query, "exclude")...),
+		MaxTokens:       maxTokens,
+		Cache:           s.cache,
+		SkipSymlinks:    true,
+		Redact:          s.redact,
+		Languages:       languages,
+	})
+
+	// The request context is cancelled when the client disconnects
+	result, err := scanner.ScanDirectoryContext(r.Context(), target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	omitted := ApplyTokenBudget(result, budget)
+	return result, omitted, nil
+}
+
+// newRequestFormatter builds a formatter from the format, tokens and tree
+// query parameters
+func newRequestFormatter(query url.Values, defaultFormat string) (*OutputFormatter, string, error) {
+	format := query.Get("format")
+	if format == "" {
+		format = defaultFormat
+	}
+	if err := ValidateFormat(format); err != nil {
+		return nil, "", newHTTPError(http.StatusBadRequest, "%v", err)
+	}
+
+	showTokens, err := boolParam(query, "tokens", true)
+	if err != nil {
+		return nil, "", err
+	}
+	showTree, err := boolParam(query, "tree", true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	formatter := NewOutputFormatter(&OutputOptions{
+		ShowTokens: showTokens,
+		ShowTree:   showTree,
+		Format:     format,
+	})
+	return formatter, format, nil
+}
+
+func (s *Server) handleRoots(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{"roots": s.names})
+}
+
+// handleScan responds with the complete output, JSON by default
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	formatter, format, err := newRequestFormatter(r.URL.Query(), "json")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, omitted, err := s.scanRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeOutput(w, formatter, format, len(omitted), result)
+}
+
+// handleDump responds with the output, plain text by default
+func (s *Server) handleDump(w http.ResponseWriter, r *http.Request) {
+	formatter, format, err := newRequestFormatter(r.URL.Query(), "text")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, omitted, err := s.scanRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeOutput(w, formatter, format, len(omitted), result)
+}
+
+func (s *Server) handleTree(w http.ResponseWriter, r *http.Request) {
+	result, _, err := s.scanRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, BuildTree(result))
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	result, omitted, err := s.scanRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	files, directories := GetTreeStats(BuildTree(result))
+	var size int64
+	for _, file := range result.Files {
+		size += file.Size
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"files":        files,
+		"directories":  directories,
+		"totalTokens":  result.TotalTokens,
+		"totalBytes":   size,
+		"summary":      GetTokenCountSummary(result.TotalTokens),
+		"omittedFiles": len(omitted),
+		"languages":    LanguageStats(result),
+	})
+}
+
+// writeOutput renders result in format before responding, so a rendering
+// error is answered with a 500 instead of a truncated output
+func writeOutput(w http.ResponseWriter, formatter *OutputFormatter, format string, omitted int, result *ScanResult) {
+	var rendered bytes.Buffer
+	if err := formatter.WriteOutput(&rendered, result); err != nil {
+		writeError(w, fmt.Errorf("failed to render output: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", formatContentType(format))
+	w.Header().Set("X-Code2txt-Omitted-Files", strconv.Itoa(omitted))
+	rendered.WriteTo(w)
+}
+
+func formatContentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "xml":
+		return "application/xml; charset=utf-8"
+	case "markdown":
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		status = httpErr.status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// listParam collects comma-separated values from all occurrences of key
+func listParam(query url.Values, key string) []string {
+	values := make([]string, 0)
+	for _, value := range query[key] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+func intParam(query url.Values, key string) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, newHTTPError(http.StatusBadRequest, "%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
+func boolParam(query url.Values, key string, defaultValue bool) (bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, newHTTPError(http.StatusBadRequest, "%s must be true or false", key)
+	}
+	return b, nil
+}
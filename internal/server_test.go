@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"api/handler.go": "package api\n",
+		"docs/readme.md": "# Docs\n",
+	})
+
+	server, err := NewServer(&ServerOptions{Roots: map[string]string{"project": root}})
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	return server, root
+}
+
+func get(server http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestServerScan(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	response := get(server, "/api/scan?include=*.go")
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", response.Code, response.Body)
+	}
+
+	var result ScanResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.TotalFiles != 2 || len(result.Files) != 2 {
+		t.Errorf("Expected 2 Go files, got %d", result.TotalFiles)
+	}
+
+	response = get(server, "/api/scan?format=markdown&path=docs")
+	if !strings.Contains(response.Body.String(), "### readme.md") {
+		t.Errorf("Expected markdown output for docs, got %s", response.Body)
+	}
+	if response.Header().Get("Content-Type") != "text/markdown; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", response.Header().Get("Content-Type"))
+	}
+}
+
+func TestServerDumpTreeAndStats(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	response := get(server, "/api/dump?root=project&tokens=false")
+	if response.Code != http.StatusOK || !strings.HasPrefix(response.Body.String(), "Directory Structure:") {
+		t.Errorf("Expected text dump, got %d: %s", response.Code, response.Body)
+	}
+
+	response = get(server, "/api/tree")
+	var tree TreeNode
+	if err := json.Unmarshal(response.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to decode tree: %v", err)
+	}
+	if len(tree.Children) != 3 {
+		t.Errorf("Expected 3 top-level entries, got %d", len(tree.Children))
+	}
+
+	response = get(server, "/api/stats")
+	var stats map[string]interface{}
+	if err := json.Unmarshal(response.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats["files"] != float64(3) {
+		t.Errorf("Expected 3 files in stats, got %v", stats["files"])
+	}
+}
+
+func TestServerBudget(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	response := get(server, "/api/scan?budget=5")
+	var result ScanResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.TotalTokens > 5 {
+		t.Errorf("Expected at most 5 tokens, got %d", result.TotalTokens)
+	}
+	if response.Header().Get("X-Code2txt-Omitted-Files") == "0" {
+		t.Error("Expected files to be omitted")
+	}
+}
+
+func TestServerConfinement(t *testing.T) {
+	server, root := newTestServer(t)
+
+	outside := t.TempDir()
+	writeTestFiles(t, outside, map[string]string{"secret.txt": "secret\n"})
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/api/scan?path=../", http.StatusForbidden},
+		{"/api/scan?path=api/../../", http.StatusForbidden},
+		{"/api/scan?path=/etc", http.StatusBadRequest},
+		{"/api/scan?path=escape", http.StatusForbidden},
+		{"/api/scan?path=missing", http.StatusNotFound},
+		{"/api/scan?root=other", http.StatusNotFound},
+		{"/api/scan?format=pdf", http.StatusBadRequest},
+		{"/api/scan?budget=-1", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if response := get(server, test.target); response.Code != test.status {
+			t.Errorf("GET %s: expected %d, got %d: %s", test.target, test.status, response.Code, response.Body)
+		}
+	}
+
+	// Symlinked files inside the root are never followed
+	response := get(server, "/api/dump")
+	if strings.Contains(response.Body.String(), "secret") {
+		t.Errorf("Expected symlinked content to be skipped, got %s", response.Body)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/scan", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", recorder.Code)
+	}
+}
+
+func TestServerRenderError(t *testing.T) {
+	tmpl, err := ParseTemplate("broken", `partial {{index .Files 5}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := NewOutputFormatter(&OutputOptions{Template: tmpl})
+
+	recorder := httptest.NewRecorder()
+	writeOutput(recorder, formatter, "text", 0, &ScanResult{RootPath: "project"})
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a render error, got %d", recorder.Code)
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "partial") || !strings.Contains(body, "failed to render output") {
+		t.Errorf("Expected only the error in the response, got %s", body)
+	}
+}
+
+func TestServerClientDisconnect(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/api/dump", nil).WithContext(ctx)
+	server.ServeHTTP(recorder, request)
+
+	if recorder.Code == http.StatusOK {
+		t.Errorf("Expected cancelled scan to fail, got %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestServerExcludeKeepsDefaults(t *testing.T) {
+	server, root := newTestServer(t)
+	writeTestFiles(t, root, map[string]string{
+		".git/config":             "[core]\n",
+		"node_modules/x/index.js": "module.exports = 1\n",
+	})
+
+	response := get(server, "/api/dump?exclude=docs")
+	body := response.Body.String()
+	if response.Code != http.StatusOK || strings.Contains(body, "readme.md") {
+		t.Errorf("Expected docs to be excluded, got %d: %s", response.Code, body)
+	}
+	if strings.Contains(body, "[core]") || strings.Contains(body, "module.exports") {
+		t.Errorf("Expected the default excludes to still apply, got %s", body)
+	}
+}
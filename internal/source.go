@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Source is something that can be scanned: a directory on disk, an
+// archive, a tree in a git repository, or any other fs.FS
+type Source struct {
+	// Name is reported as the root path of the scan
+	Name string
+	// FS holds the files to scan
+	FS fs.FS
+	// OnDisk is set for directories on disk, which can use the cache
+	OnDisk bool
+
+	closer io.Closer
+}
+
+// NewDirSource returns a source for the directory dir on disk
+func NewDirSource(dir string) *Source {
+	return &Source{Name: dir, FS: os.DirFS(dir), OnDisk: true}
+}
+
+// NewFSSource returns a source for the files in fsys. name is reported as
+// the root path of the scan.
+func NewFSSource(name string, fsys fs.FS) *Source {
+	return &Source{Name: name, FS: fsys}
+}
+
+// OpenSource opens path for scanning. Directories are read from disk and
+// archives through OpenArchive. With a ref, or when path is a bare
+// repository, files are read from git at that ref (HEAD by default), and
+// cancelling ctx stops reading them.
+func OpenSource(ctx context.Context, path, ref string) (*Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("folder does not exist: %s", path)
+		}
+		return nil, err
+	}
+
+	if info.IsDir() {
+		if ref == "" && IsBareGitRepo(path) {
+			ref = "HEAD"
+		}
+		if ref == "" {
+			return NewDirSource(path), nil
+		}
+
+		fsys, err := OpenGitTree(ctx, path, ref)
+		if err != nil {
+			return nil, err
+		}
+		return NewFSSource(path+"@"+ref, fsys), nil
+	}
+
+	if ref != "" {
+		return nil, fmt.Errorf("--ref requires a git repository: %s", path)
+	}
+	if !IsArchive(path) {
+		return nil, fmt.Errorf("not a directory or a .zip, .tar, .tar.gz or .tgz archive: %s", path)
+	}
+
+	fsys, closer, err := OpenArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	src := NewFSSource(path, fsys)
+	src.closer = closer
+	return src, nil
+}
+
+// Close releases the resources held by the source
+func (src *Source) Close() error {
+	if src.closer == nil {
+		return nil
+	}
+	return src.closer.Close()
+}
@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var archiveTestFiles = map[string]string{
+	"main.go":        "package main\n",
+	"src/utils.go":   "package src\n",
+	"docs/readme.md": "# Docs\n",
+}
+
+// scanContents scans src and returns the contents keyed by slash path
+func scanContents(t *testing.T, src *Source) map[string]string {
+	t.Helper()
+	defer src.Close()
+
+	result, err := NewScanner(&ScanOptions{}).ScanSource(context.Background(), src)
+	if err != nil {
+		t.Fatalf("ScanSource failed: %v", err)
+	}
+
+	contents := make(map[string]string)
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			contents[filepath.ToSlash(file.RelativePath)] = file.Content
+		}
+	}
+	return contents
+}
+
+func checkContents(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("Expected %d files, got %d: %v", len(want), len(got), got)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, got[name])
+		}
+	}
+}
+
+func sortedNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, name := range sortedNames(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		io.WriteString(w, files[name])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func writeTar(t *testing.T, path string, files map[string]string, compress bool) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer out.Close()
+
+	var w io.Writer = out
+	if compress {
+		gz := gzip.NewWriter(out)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range sortedNames(files) {
+		header := &tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		io.WriteString(tw, files[name])
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestScanArchives(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name  string
+		write func(path string)
+	}{
+		{"project.zip", func(path string) { writeZip(t, path, archiveTestFiles) }},
+		{"project.tar", func(path string) { writeTar(t, path, archiveTestFiles, false) }},
+		{"project.tar.gz", func(path string) { writeTar(t, path, archiveTestFiles, true) }},
+		{"project.tgz", func(path string) { writeTar(t, path, archiveTestFiles, true) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.name)
+			tt.write(path)
+
+			src, err := OpenSource(context.Background(), path, "")
+			if err != nil {
+				t.Fatalf("OpenSource failed: %v", err)
+			}
+			if src.OnDisk {
+				t.Error("Expected an archive not to be treated as a directory on disk")
+			}
+			checkContents(t, scanContents(t, src), archiveTestFiles)
+		})
+	}
+}
+
+func TestTarRejectsEscapingPaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evil.tar")
+	writeTar(t, path, map[string]string{
+		"../outside.txt": "escaped\n",
+		"inside.txt":     "kept\n",
+	}, false)
+
+	src, err := OpenSource(context.Background(), path, "")
+	if err != nil {
+		t.Fatalf("OpenSource failed: %v", err)
+	}
+	checkContents(t, scanContents(t, src), map[string]string{"inside.txt": "kept\n"})
+}
+
+func TestOpenSourceRejectsUnknownFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("hello"), 0644)
+
+	if _, err := OpenSource(context.Background(), path, ""); err == nil {
+		t.Error("Expected an error for a file that is not an archive")
+	}
+	if _, err := OpenSource(context.Background(), filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestScanGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	writeTestFiles(t, repo, map[string]string{
+		"main.go":      "package main // v1\n",
+		"src/utils.go": "package src\n",
+	})
+	git(t, repo, "init", "-q")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "v1")
+	git(t, repo, "tag", "v1")
+
+	// Later changes, committed and uncommitted, must not show up at v1
+	writeTestFiles(t, repo, map[string]string{"main.go": "package main // v2\n", "new.go": "package main\n"})
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "v2")
+	writeTestFiles(t, repo, map[string]string{"main.go": "package main // dirty\n"})
+
+	src, err := OpenSource(context.Background(), repo, "v1")
+	if err != nil {
+		t.Fatalf("OpenSource failed: %v", err)
+	}
+	checkContents(t, scanContents(t, src), map[string]string{
+		"main.go":      "package main // v1\n",
+		"src/utils.go": "package src\n",
+	})
+
+	// A bare clone is read at HEAD without a ref
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	git(t, repo, "clone", "-q", "--bare", repo, bare)
+
+	src, err = OpenSource(context.Background(), bare, "")
+	if err != nil {
+		t.Fatalf("OpenSource failed for bare repo: %v", err)
+	}
+	checkContents(t, scanContents(t, src), map[string]string{
+		"main.go":      "package main // v2\n",
+		"new.go":       "package main\n",
+		"src/utils.go": "package src\n",
+	})
+
+	if _, err := OpenSource(context.Background(), repo, "no-such-ref"); err == nil {
+		t.Error("Expected an error for an unknown ref")
+	}
+	if _, err := OpenSource(context.Background(), repo, "--output=x"); err == nil {
+		t.Error("Expected an error for a ref that looks like an option")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := OpenSource(ctx, repo, "v1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected reading a ref to stop when cancelled, got %v", err)
+	}
+}
+
+func TestArchiveReportsLargeFiles(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "small.txt", Mode: 0644, Size: 6, Typeflag: tar.TypeReg})
+	io.WriteString(tw, "small\n")
+	tw.WriteHeader(&tar.Header{Name: "data/huge.bin", Mode: 0644, Size: maxFileSize + 1, Typeflag: tar.TypeReg})
+	tw.Write(make([]byte, maxFileSize+1))
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	fsys, err := readTar(&archive)
+	if err != nil {
+		t.Fatalf("readTar failed: %v", err)
+	}
+	if info, err := fs.Stat(fsys, "data/huge.bin"); err != nil || info.Size() != maxFileSize+1 {
+		t.Fatalf("Expected a stub with the entry size, got %v, %v", info, err)
+	}
+
+	skipped := make(map[string]string)
+	scanner := NewScanner(&ScanOptions{Skipped: func(relPath, reason string) {
+		skipped[filepath.ToSlash(relPath)] = reason
+	}})
+	result, err := scanner.ScanFS(context.Background(), fsys, "archive")
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	if result.TotalFiles != 1 {
+		t.Errorf("Expected only small.txt to be scanned, got %d files", result.TotalFiles)
+	}
+	if skipped["data/huge.bin"] != SkipTooLarge {
+		t.Errorf("Expected huge.bin to be skipped as too large, got %v", skipped)
+	}
+}
+
+func TestGitTreeReportsLargeFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	writeTestFiles(t, repo, map[string]string{"main.go": "package main\n"})
+	if err := os.WriteFile(filepath.Join(repo, "huge.bin"), make([]byte, maxFileSize+1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, repo, "init", "-q")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "-q", "-m", "v1")
+
+	fsys, err := OpenGitTree(context.Background(), repo, "HEAD")
+	if err != nil {
+		t.Fatalf("OpenGitTree failed: %v", err)
+	}
+	if info, err := fs.Stat(fsys, "huge.bin"); err != nil || info.Size() != maxFileSize+1 {
+		t.Errorf("Expected a stub with the blob size, got %v, %v", info, err)
+	}
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// partNote is the "part i of n" note written at the top of every part of a
+// split dump
+func partNote(part, parts int) string {
+	return fmt.Sprintf("Part %d of %d", part, parts)
+}
+
+// Split divides result into parts whose files, prompt and part note fit in
+// maxTokens each, counted as f renders them, with line numbers when they
+// are enabled. Files stay in path order. A file too large for a part is
+// cut at line boundaries, and each piece records its first line in
+// StartLine so numbering continues across parts; a single line larger than
+// a part gets a part of its own. The prompt header is repeated in every
+// part and the footer closes the last one, but both are reserved in every
+// part. A result that fits is returned as the only part.
+func (f *OutputFormatter) Split(result *ScanResult, maxTokens int) ([]*ScanResult, error) {
+	reserved := f.options.Prompt.Tokens() + CountTokens(partNote(999, 999))
+	budget := maxTokens - reserved
+	if budget <= 0 {
+		return nil, fmt.Errorf("the prompt alone uses %d tokens, the split size is %d", reserved, maxTokens)
+	}
+	if f.Prepare(result).TotalTokens <= budget {
+		return []*ScanResult{result}, nil
+	}
+
+	var parts [][]*FileInfo
+	var current []*FileInfo
+	used := 0
+	flush := func() {
+		if len(current) > 0 {
+			parts = append(parts, current)
+		}
+		current = nil
+		used = 0
+	}
+
+	for _, file := range contentFiles(result) {
+		file = sourceFile(file)
+		tokens := f.contentTokens(file.Content, file.StartLine)
+		if tokens <= budget-used {
+			current = append(current, file)
+			used += tokens
+			continue
+		}
+		if tokens <= budget {
+			flush()
+			current = append(current, file)
+			used = tokens
+			continue
+		}
+
+		// Too large for any part, cut it into pieces
+		lines := strings.SplitAfter(file.Content, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		start := file.StartLine
+		if start < 1 {
+			start = 1
+		}
+		for i := 0; i < len(lines); {
+			room := budget - used
+
+			// Estimate line by line, then check the piece as a whole
+			j, estimate := i, 0
+			for j < len(lines) {
+				lineTokens := f.contentTokens(lines[j], start+j)
+				if j > i && estimate+lineTokens > room {
+					break
+				}
+				estimate += lineTokens
+				j++
+			}
+			tokens := f.contentTokens(strings.Join(lines[i:j], ""), start+i)
+			for j > i+1 && tokens > room {
+				j--
+				tokens = f.contentTokens(strings.Join(lines[i:j], ""), start+i)
+			}
+			if tokens > room && used > 0 {
+				// Not even one line fits, start a new part
+				flush()
+				continue
+			}
+
+			piece := *file
+			piece.Content = strings.Join(lines[i:j], "")
+			piece.StartLine = start + i
+			piece.TokenCount = CountTokens(piece.Content)
+			current = append(current, &piece)
+			used += tokens
+			if j < len(lines) {
+				flush()
+			}
+			i = j
+		}
+	}
+	flush()
+
+	results := make([]*ScanResult, len(parts))
+	for i, files := range parts {
+		results[i] = result.part(files, i+1, len(parts))
+	}
+	return results, nil
+}
+
+// prompt returns the prompt written around result: the footer is left out
+// of all but the last part of a split dump
+func (f *OutputFormatter) prompt(result *ScanResult) *Prompt {
+	if f.options.Prompt == nil || result.Part >= result.Parts {
+		return f.options.Prompt
+	}
+	return &Prompt{Header: f.options.Prompt.Header}
+}
+
+// contentTokens counts the tokens of content as rendered, starting at line
+// start
+func (f *OutputFormatter) contentTokens(content string, start int) int {
+	if f.options.LineNumbers {
+		content = NumberLines(content, start)
+	}
+	return CountTokens(content)
+}
+
+// part returns the part of a split result holding files, with the
+// directories that lead to them
+func (r *ScanResult) part(files []*FileInfo, part, parts int) *ScanResult {
+	result := &ScanResult{
+		RootPath:   r.RootPath,
+		Incomplete: r.Incomplete,
+		Roots:      r.Roots,
+		Part:       part,
+		Parts:      parts,
+		NamesOnly:  r.NamesOnly,
+	}
+
+	dirs := fileDirs(files)
+	for _, file := range r.Files {
+		if file.IsDirectory && (dirs[file.RelativePath] || file.RelativePath == ".") {
+			result.Files = append(result.Files, file)
+		}
+	}
+	result.Files = append(result.Files, files...)
+	result.recount()
+	return result
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func splitTestResult() *ScanResult {
+	result := &ScanResult{RootPath: "project"}
+	result.Files = append(result.Files, &FileInfo{RelativePath: "pkg", IsDirectory: true})
+	for _, name := range []string{"a.go", "b.go", "c.go", filepath.Join("pkg", "d.go")} {
+		content := strings.Repeat("func "+strings.TrimSuffix(filepath.Base(name), ".go")+"() {}\n", 10)
+		result.Files = append(result.Files, &FileInfo{RelativePath: name, Content: content, TokenCount: CountTokens(content)})
+	}
+	result.recount()
+	return result
+}
+
+func TestSplit(t *testing.T) {
+	const maxTokens = 120
+	prompt := &Prompt{Header: "Review this code.", Footer: "What is wrong?"}
+	formatter := NewOutputFormatter(&OutputOptions{Prompt: prompt})
+	result := splitTestResult()
+
+	parts, err := formatter.Split(result, maxTokens)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("Expected several parts, got %d", len(parts))
+	}
+
+	files := 0
+	for i, part := range parts {
+		if part.Part != i+1 || part.Parts != len(parts) {
+			t.Errorf("Unexpected numbering %d of %d for part %d", part.Part, part.Parts, i+1)
+		}
+		// Files, prompt and note fit in the split size
+		if used := part.TotalTokens + prompt.Tokens() + CountTokens(partNote(part.Part, part.Parts)); used > maxTokens {
+			t.Errorf("Part %d uses %d tokens, more than %d", i+1, used, maxTokens)
+		}
+		files += part.TotalFiles
+
+		output := formatter.FormatOutput(part)
+		note := fmt.Sprintf("Part %d of %d", i+1, len(parts))
+		if !strings.HasPrefix(output, "Review this code.\n\n"+note+"\n") {
+			t.Errorf("Expected the header and %q at the top of part %d:\n%s", note, i+1, output)
+		}
+		if last := i == len(parts)-1; strings.Contains(output, "What is wrong?") != last {
+			t.Errorf("Expected the footer only in the last part, part %d:\n%s", i+1, output)
+		}
+	}
+	if files != result.TotalFiles {
+		t.Errorf("Expected the %d files spread over the parts, got %d", result.TotalFiles, files)
+	}
+
+	// A dump that fits is not split
+	parts, err = formatter.Split(result, 10000)
+	if err != nil || len(parts) != 1 || parts[0] != result {
+		t.Errorf("Expected the result as the only part, got %d parts, %v", len(parts), err)
+	}
+
+	if _, err := formatter.Split(result, prompt.Tokens()); err == nil {
+		t.Error("Expected an error when the prompt does not fit")
+	}
+}
+
+func TestSplitLargeFile(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&content, "value%d := compute(%d)\n", i, i)
+	}
+	result := &ScanResult{RootPath: "project", Files: []*FileInfo{
+		{RelativePath: "big.go", Content: content.String(), TokenCount: CountTokens(content.String())},
+	}}
+	result.recount()
+
+	formatter := NewOutputFormatter(&OutputOptions{LineNumbers: true})
+	parts, err := formatter.Split(formatter.Prepare(result), 150)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("Expected the file to be split, got %d parts", len(parts))
+	}
+
+	var joined strings.Builder
+	next := 1
+	for i, part := range parts {
+		piece := contentFiles(part)[0]
+		if piece.StartLine != next {
+			t.Errorf("Expected part %d to start at line %d, got %d", i+1, next, piece.StartLine)
+		}
+		output := formatter.FormatOutput(part)
+		if want := fmt.Sprintf("%2d | value%d ", next, next); !strings.Contains(output, want) {
+			t.Errorf("Expected numbering to continue with %q in part %d:\n%s", want, i+1, output)
+		}
+		if tokens := formatter.Prepare(part).TotalTokens; tokens > 150 {
+			t.Errorf("Part %d uses %d tokens", i+1, tokens)
+		}
+		joined.WriteString(piece.Content)
+		next += strings.Count(piece.Content, "\n")
+	}
+	if joined.String() != content.String() {
+		t.Error("Expected the pieces to add up to the file")
+	}
+}
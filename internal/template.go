@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// TemplateData is the value output templates are executed with
+type TemplateData struct {
+	// Result is the scan being rendered
+	Result *ScanResult
+	// Name is the base name of the scanned root
+	Name string
+	// Tree is the rendered directory tree, empty unless ShowTree is set
+	Tree string
+	// Files are the files of Result without directories, sorted by path
+	Files []*FileInfo
+	// Warning explains why the output is incomplete, empty otherwise
+	Warning string
+	// Header and Footer are the prompt written around the dump. In a split
+	// dump every part repeats the header and only the last has the footer.
+	Header string
+	Footer string
+	// Part is the "Part i of n" note of a split dump, empty otherwise
+	Part string
+	// TotalTokens counts the files and the prompt
+	TotalTokens int
+	ShowTokens  bool
+	ShowTree    bool
+}
+
+// TemplateFuncs returns the helper functions available to output
+// templates:
+//
+//	language FILE     the code fence language of a file
+//	fence TEXT        a markdown code fence longer than any backtick run in TEXT
+//	indent N TEXT     TEXT with every non-empty line indented by N spaces
+//	tokens TEXT       the estimated token count of TEXT
+//	humanize N        N shortened to 1.2k or 3.4M
+//	summary N         the size class of N tokens, such as Small or Large
+//	repeat S N        S repeated N times
+//	terminate TEXT    TEXT with a trailing newline unless it is empty
+//	slash PATH        PATH with forward slashes
+//	xml TEXT          TEXT escaped for XML content
+//	xmlattr TEXT      TEXT escaped for an XML attribute
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"language": fenceLanguage,
+		"fence":    markdownFence,
+		"indent":   indentLines,
+		"tokens":   CountTokens,
+		"humanize": formatNumber,
+		"summary":  GetTokenCountSummary,
+		"repeat":   func(s string, n int) string { return strings.Repeat(s, n) },
+		"terminate": func(text string) string {
+			if text != "" && !strings.HasSuffix(text, "\n") {
+				return text + "\n"
+			}
+			return text
+		},
+		"slash":   filepath.ToSlash,
+		"xml":     xmlEscaper.Replace,
+		"xmlattr": xmlAttrEscaper.Replace,
+	}
+}
+
+// ParseTemplate parses an output template with the TemplateFuncs helpers
+func ParseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// TemplateNames lists the output formats that are rendered by a built-in
+// template
+func TemplateNames() []string {
+	entries, _ := builtinTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".tmpl"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinTemplate returns the source of the template behind an output
+// format, to be exported and modified
+func BuiltinTemplate(name string) (string, error) {
+	data, err := builtinTemplates.ReadFile("templates/" + name + ".tmpl")
+	if err != nil {
+		return "", fmt.Errorf("unknown template %q (available: %s)",
+			name, strings.Join(TemplateNames(), ", "))
+	}
+	return string(data), nil
+}
+
+// parsedTemplates holds the built-in templates, parsed once
+var parsedTemplates = func() map[string]*template.Template {
+	parsed := make(map[string]*template.Template)
+	for _, name := range TemplateNames() {
+		text, _ := BuiltinTemplate(name)
+		parsed[name] = template.Must(ParseTemplate(name, text))
+	}
+	return parsed
+}()
+
+// writeTemplate executes tmpl for result
+func (f *OutputFormatter) writeTemplate(w io.Writer, tmpl *template.Template, result *ScanResult) error {
+	return tmpl.Execute(w, f.templateData(result))
+}
+
+// templateData returns the value templates are executed with for result
+func (f *OutputFormatter) templateData(result *ScanResult) *TemplateData {
+	data := &TemplateData{
+		Result:     result,
+		Name:       filepath.Base(result.RootPath),
+		Files:      contentFiles(result),
+		ShowTokens: f.options.ShowTokens,
+		ShowTree:   f.options.ShowTree,
+	}
+	prompt := f.prompt(result)
+	data.TotalTokens = result.TotalTokens + prompt.Tokens()
+	if prompt != nil {
+		data.Header = prompt.Header
+		data.Footer = prompt.Footer
+	}
+	if result.Parts > 0 {
+		data.Part = partNote(result.Part, result.Parts)
+	}
+	if f.options.ShowTree {
+		data.Tree = renderTrees(result, f.treeOptions())
+	}
+	if result.Incomplete {
+		data.Warning = strings.TrimSpace(incompleteMarker(result))
+	}
+	return data
+}
+
+// indentLines indents every non-empty line of text by n spaces
+func indentLines(n int, text string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "")
+}
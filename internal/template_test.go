@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	for _, name := range []string{"text", "markdown", "xml"} {
+		text, err := BuiltinTemplate(name)
+		if err != nil {
+			t.Fatalf("Expected a built-in %s template: %v", name, err)
+		}
+		if _, err := ParseTemplate(name, text); err != nil {
+			t.Errorf("Failed to parse the exported %s template: %v", name, err)
+		}
+	}
+	if _, err := BuiltinTemplate("json"); err == nil {
+		t.Error("Expected an error for a format without a template")
+	}
+}
+
+func TestTemplateIncomplete(t *testing.T) {
+	result := formatTestResult()
+	result.Incomplete = true
+
+	output := NewOutputFormatter(&OutputOptions{}).FormatOutput(result)
+	if !strings.HasPrefix(output, incompleteMarker(result)) || !strings.HasSuffix(output, "\n"+incompleteMarker(result)) {
+		t.Errorf("Expected the incomplete marker before and after the output, got:\n%s", output)
+	}
+}
+
+func TestCustomTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("custom", `# {{.Name}} ({{humanize .Result.TotalTokens}})
+{{range .Files}}== {{.RelativePath}} [{{language .}}]
+{{indent 2 .Content | terminate}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := NewOutputFormatter(&OutputOptions{Format: "json", Template: tmpl})
+	output := formatter.FormatOutput(formatTestResult())
+	expected := "# project (12)\n== README.md [md]\n  Use ```go``` and <b>tags</b> & more\n== main.go [go]\n  package main\n"
+	if output != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if _, err := ParseTemplate("broken", "{{range .Files}}"); err == nil {
+		t.Error("Expected an error for an unterminated range")
+	}
+}
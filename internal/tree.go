@@ -9,12 +9,20 @@ import (
 
 // TreeNode represents a node in the directory tree
 type TreeNode struct {
-	Name        string
-	Path        string
-	IsDirectory bool
-	TokenCount  int
-	Children    []*TreeNode
-	Parent      *TreeNode
+	Name        string `json:"name"`
+	Path        string `json:"-"`
+	IsDirectory bool   `json:"isDirectory,omitempty"`
+	TokenCount  int    `json:"tokens"`
+	Size        int64  `json:"-"`
+	// TotalTokens, TotalSize and Files add up everything below a
+	// directory; BuildTree computes them once for the whole tree
+	TotalTokens int         `json:"totalTokens,omitempty"`
+	TotalSize   int64       `json:"totalSize,omitempty"`
+	Files       int         `json:"files,omitempty"`
+	Role        string      `json:"role,omitempty"`
+	Class       string      `json:"class,omitempty"`
+	Children    []*TreeNode `json:"children,omitempty"`
+	Parent      *TreeNode   `json:"-"`
 }
 
 // BuildTree creates a tree structure from the scan results
@@ -34,7 +42,7 @@ func BuildTree(result *ScanResult) *TreeNode {
 	sortedFiles := make([]*FileInfo, len(result.Files))
 	copy(sortedFiles, result.Files)
 	sort.Slice(sortedFiles, func(i, j int) bool {
-		return sortedFiles[i].RelativePath < sortedFiles[j].RelativePath
+		return filepath.ToSlash(sortedFiles[i].RelativePath) < filepath.ToSlash(sortedFiles[j].RelativePath)
 	})
 
 	// Build tree structure
@@ -66,6 +74,9 @@ func BuildTree(result *ScanResult) *TreeNode {
 
 				if !node.IsDirectory {
 					node.TokenCount = file.TokenCount
+					node.Size = file.Size
+					node.Role = file.Role
+					node.Class = file.Class
 				}
 
 				// Find parent
@@ -85,10 +96,48 @@ func BuildTree(result *ScanResult) *TreeNode {
 
 	// Sort children in each node
 	sortTreeChildren(root)
+	addTotals(root)
 
 	return root
 }
 
+// addTotals sets the totals of every directory below node, in one pass
+func addTotals(node *TreeNode) {
+	if !node.IsDirectory {
+		return
+	}
+	node.TotalTokens, node.TotalSize, node.Files = 0, 0, 0
+	for _, child := range node.Children {
+		addTotals(child)
+		node.TotalTokens += child.totalTokens()
+		node.TotalSize += child.totalSize()
+		node.Files += child.fileCount()
+	}
+}
+
+// totalTokens returns the tokens of a file, or of everything below a
+// directory
+func (node *TreeNode) totalTokens() int {
+	if node.IsDirectory {
+		return node.TotalTokens
+	}
+	return node.TokenCount
+}
+
+func (node *TreeNode) totalSize() int64 {
+	if node.IsDirectory {
+		return node.TotalSize
+	}
+	return node.Size
+}
+
+func (node *TreeNode) fileCount() int {
+	if node.IsDirectory {
+		return node.Files
+	}
+	return 1
+}
+
 func sortTreeChildren(node *TreeNode) {
 	// Sort children: directories first, then files, both alphabetically
 	sort.Slice(node.Children, func(i, j int) bool {
@@ -107,15 +156,98 @@ func sortTreeChildren(node *TreeNode) {
 	}
 }
 
+// Orders accepted by SortTree, see TreeSortOrders
+const (
+	TreeSortName   = "name"
+	TreeSortSize   = "size"
+	TreeSortTokens = "tokens"
+)
+
+// TreeSortOrders lists the orders accepted by SortTree
+func TreeSortOrders() []string {
+	return []string{TreeSortName, TreeSortSize, TreeSortTokens}
+}
+
+// ValidateTreeSort checks that by is one of TreeSortOrders
+func ValidateTreeSort(by string) error {
+	if by == "" {
+		return nil
+	}
+	for _, known := range TreeSortOrders() {
+		if by == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown tree order %q (available: %s)",
+		by, strings.Join(TreeSortOrders(), ", "))
+}
+
+// SortTree reorders the children of every node. By name, directories come
+// first as in BuildTree; by size or tokens, the largest entries come first
+// with directories counting everything below them.
+func SortTree(root *TreeNode, by string) {
+	var total func(node *TreeNode) int64
+	switch by {
+	case TreeSortSize:
+		total = (*TreeNode).totalSize
+	case TreeSortTokens:
+		total = func(node *TreeNode) int64 { return int64(node.totalTokens()) }
+	default:
+		sortTreeChildren(root)
+		return
+	}
+
+	var sortNode func(node *TreeNode)
+	sortNode = func(node *TreeNode) {
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			a, b := node.Children[i], node.Children[j]
+			if total(a) != total(b) {
+				return total(a) > total(b)
+			}
+			return a.Name < b.Name
+		})
+		for _, child := range node.Children {
+			sortNode(child)
+		}
+	}
+	sortNode(root)
+}
+
+// TreeOptions controls what RenderTreeOptions shows
+type TreeOptions struct {
+	// ShowTokens annotates files with their token counts, and directories
+	// with their file count and total tokens
+	ShowTokens bool
+	// ShowSize annotates files with their size, and directories with their
+	// file count and total size
+	ShowSize bool
+	// MaxDepth hides the nodes more than this many levels below the root,
+	// 0 shows the whole tree
+	MaxDepth int
+	// DirsOnly leaves files out
+	DirsOnly bool
+	// Collapse folds the small files of a directory holding more than this
+	// many files into a single line with their totals, 0 never folds. Files
+	// with a tenth or more of the tokens or bytes of the directory's files
+	// stay visible.
+	Collapse int
+}
+
 // RenderTree generates a tree-like string representation
 func RenderTree(root *TreeNode, showTokens bool) string {
+	return RenderTreeOptions(root, TreeOptions{ShowTokens: showTokens})
+}
+
+// RenderTreeOptions renders root like RenderTree, with the annotations,
+// depth and filtering of options
+func RenderTreeOptions(root *TreeNode, options TreeOptions) string {
 	var result strings.Builder
-	renderNode(root, "", true, true, showTokens, &result)
+	renderNode(root, "", true, 0, options, &result)
 	return result.String()
 }
 
-func renderNode(node *TreeNode, prefix string, isLast bool, isRoot bool, showTokens bool, result *strings.Builder) {
-	if !isRoot {
+func renderNode(node *TreeNode, prefix string, isLast bool, depth int, options TreeOptions, result *strings.Builder) {
+	if depth > 0 {
 		// Determine the tree characters
 		var connector string
 		if isLast {
@@ -125,9 +257,9 @@ func renderNode(node *TreeNode, prefix string, isLast bool, isRoot bool, showTok
 		}
 
 		// Write the node line
-		line := prefix + connector + node.Name
-		if showTokens && !node.IsDirectory && node.TokenCount > 0 {
-			line += fmt.Sprintf(" (%d tokens)", node.TokenCount)
+		line := prefix + connector + node.Name + node.annotation(options)
+		if tags := node.tags(); len(tags) > 0 {
+			line += " [" + strings.Join(tags, ", ") + "]"
 		}
 		result.WriteString(line + "\n")
 
@@ -139,29 +271,131 @@ func renderNode(node *TreeNode, prefix string, isLast bool, isRoot bool, showTok
 		}
 	} else {
 		// Root node
-		line := node.Name
-		if showTokens {
-			totalTokens := calculateTotalTokens(node)
-			if totalTokens > 0 {
-				line += fmt.Sprintf(" (%d tokens)", totalTokens)
+		result.WriteString(node.Name + node.annotation(options) + "\n")
+	}
+
+	if options.MaxDepth > 0 && depth >= options.MaxDepth {
+		return
+	}
+
+	// Render children, folding the small files of crowded directories
+	children := make([]*TreeNode, 0, len(node.Children))
+	files := make([]*TreeNode, 0, len(node.Children))
+	for _, child := range node.Children {
+		if !child.IsDirectory {
+			if options.DirsOnly {
+				continue
 			}
+			files = append(files, child)
 		}
-		result.WriteString(line + "\n")
+		children = append(children, child)
+	}
+	var folded []*TreeNode
+	if options.Collapse > 0 && len(files) > options.Collapse {
+		folded = smallFiles(files)
+	}
+	if len(folded) > 1 {
+		small := make(map[*TreeNode]bool, len(folded))
+		for _, file := range folded {
+			small[file] = true
+		}
+		shown := children[:0]
+		for _, child := range children {
+			if !small[child] {
+				shown = append(shown, child)
+			}
+		}
+		children = shown
+	} else {
+		folded = nil
 	}
 
-	// Render children
-	for i, child := range node.Children {
-		isLastChild := i == len(node.Children)-1
-		renderNode(child, prefix, isLastChild, false, showTokens, result)
+	for i, child := range children {
+		isLastChild := i == len(children)-1 && folded == nil
+		renderNode(child, prefix, isLastChild, depth+1, options, result)
+	}
+	if folded != nil {
+		result.WriteString(prefix + "└── …" + foldAnnotation(folded, options) + "\n")
 	}
 }
 
-func calculateTotalTokens(node *TreeNode) int {
-	total := node.TokenCount
-	for _, child := range node.Children {
-		total += calculateTotalTokens(child)
+// smallFiles returns the files that hold less than a tenth of both the
+// tokens and the bytes of files, which a crowded directory folds. Larger
+// files stay visible.
+func smallFiles(files []*TreeNode) []*TreeNode {
+	var size int64
+	tokens := 0
+	for _, file := range files {
+		size += file.Size
+		tokens += file.TokenCount
+	}
+
+	var small []*TreeNode
+	for _, file := range files {
+		if (size == 0 || file.Size*10 < size) && (tokens == 0 || file.TokenCount*10 < tokens) {
+			small = append(small, file)
+		}
+	}
+	return small
+}
+
+// annotation returns the size and token counts shown after the name of a
+// node, empty when there are none
+func (node *TreeNode) annotation(options TreeOptions) string {
+	var parts []string
+	if node.IsDirectory && (options.ShowSize || options.ShowTokens) {
+		parts = append(parts, pluralFiles(node.Files))
+	}
+	if options.ShowSize {
+		parts = append(parts, formatBytes(node.totalSize()))
+	}
+	if tokens := node.totalTokens(); options.ShowTokens && tokens > 0 {
+		parts = append(parts, formatNumber(tokens)+" tokens")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// foldAnnotation sums up the files folded into one line, e.g.
+// " (37 files, 12.4k tokens)"
+func foldAnnotation(files []*TreeNode, options TreeOptions) string {
+	var size int64
+	tokens := 0
+	for _, file := range files {
+		size += file.Size
+		tokens += file.TokenCount
+	}
+
+	parts := []string{pluralFiles(len(files))}
+	if options.ShowSize {
+		parts = append(parts, formatBytes(size))
+	}
+	if options.ShowTokens && tokens > 0 {
+		parts = append(parts, formatNumber(tokens)+" tokens")
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// tags lists the role and class shown next to a file. Source files are the
+// norm and are not tagged.
+func (node *TreeNode) tags() []string {
+	var tags []string
+	if node.Role != "" {
+		tags = append(tags, node.Role)
+	}
+	if node.Class != "" && node.Class != ClassSource {
+		tags = append(tags, node.Class)
 	}
-	return total
+	return tags
 }
 
 // GetTreeStats returns statistics about the tree
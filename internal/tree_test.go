@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func treeTestResult() *ScanResult {
+	return &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Size: 300, TokenCount: 30},
+			{RelativePath: "lib", IsDirectory: true},
+			{RelativePath: filepath.Join("lib", "big.go"), Size: 2048, TokenCount: 500},
+			{RelativePath: filepath.Join("lib", "deep"), IsDirectory: true},
+			{RelativePath: filepath.Join("lib", "deep", "small.go"), Size: 100, TokenCount: 10},
+			{RelativePath: "zz.go", Size: 4096, TokenCount: 40},
+		},
+	}
+}
+
+func TestRenderTreeOptions(t *testing.T) {
+	tree := BuildTree(treeTestResult())
+
+	if RenderTree(tree, true) != RenderTreeOptions(tree, TreeOptions{ShowTokens: true}) {
+		t.Error("Expected RenderTree to match RenderTreeOptions with only ShowTokens")
+	}
+
+	output := RenderTreeOptions(tree, TreeOptions{ShowTokens: true, ShowSize: true, MaxDepth: 1})
+	expected := "project (4 files, 6.4 KB, 580 tokens)\n" +
+		"├── lib (2 files, 2.1 KB, 510 tokens)\n" +
+		"├── main.go (300 B, 30 tokens)\n" +
+		"└── zz.go (4.0 KB, 40 tokens)\n"
+	if output != expected {
+		t.Errorf("Unexpected depth-limited tree:\n%s\nexpected:\n%s", output, expected)
+	}
+
+	output = RenderTreeOptions(tree, TreeOptions{DirsOnly: true})
+	expected = "project\n" +
+		"└── lib\n" +
+		"    └── deep\n"
+	if output != expected {
+		t.Errorf("Unexpected directory tree:\n%s\nexpected:\n%s", output, expected)
+	}
+
+	// The files of the root are too large to fold
+	output = RenderTreeOptions(tree, TreeOptions{ShowTokens: true, Collapse: 1})
+	if !strings.Contains(output, "└── zz.go (40 tokens)\n") || strings.Contains(output, "…") {
+		t.Errorf("Expected zz.go to stay visible:\n%s", output)
+	}
+}
+
+func TestRenderTreeCollapse(t *testing.T) {
+	result := &ScanResult{RootPath: "project"}
+	result.Files = append(result.Files, &FileInfo{RelativePath: "gen", IsDirectory: true})
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		result.Files = append(result.Files, &FileInfo{RelativePath: filepath.Join("gen", name), Size: 100, TokenCount: 20})
+	}
+	result.Files = append(result.Files, &FileInfo{RelativePath: filepath.Join("gen", "big.go"), Size: 8000, TokenCount: 1500})
+	tree := BuildTree(result)
+
+	output := RenderTreeOptions(tree, TreeOptions{ShowTokens: true, Collapse: 3})
+	expected := "project (5 files, 1.6k tokens)\n" +
+		"└── gen (5 files, 1.6k tokens)\n" +
+		"    ├── big.go (1.5k tokens)\n" +
+		"    └── … (4 files, 80 tokens)\n"
+	if output != expected {
+		t.Errorf("Unexpected collapsed tree:\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestBuildTreeTotals(t *testing.T) {
+	tree := BuildTree(treeTestResult())
+	lib := tree.Children[0]
+	if tree.TotalTokens != 580 || tree.TotalSize != 6544 || tree.Files != 4 {
+		t.Errorf("Unexpected root totals: %d tokens, %d bytes, %d files", tree.TotalTokens, tree.TotalSize, tree.Files)
+	}
+	if lib.Name != "lib" || lib.TotalTokens != 510 || lib.Files != 2 {
+		t.Errorf("Unexpected totals for %s: %d tokens, %d files", lib.Name, lib.TotalTokens, lib.Files)
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	tree := BuildTree(treeTestResult())
+
+	SortTree(tree, TreeSortTokens)
+	if names := childNames(tree); names != "lib zz.go main.go" {
+		t.Errorf("Expected the most tokens first, got %s", names)
+	}
+
+	SortTree(tree, TreeSortSize)
+	if names := childNames(tree); names != "zz.go lib main.go" {
+		t.Errorf("Expected the largest first, got %s", names)
+	}
+
+	SortTree(tree, TreeSortName)
+	if names := childNames(tree); names != "lib main.go zz.go" {
+		t.Errorf("Expected directories first, then names, got %s", names)
+	}
+
+	if err := ValidateTreeSort("date"); err == nil {
+		t.Error("Expected an error for an unknown order")
+	}
+}
+
+func childNames(node *TreeNode) string {
+	names := make([]string, len(node.Children))
+	for i, child := range node.Children {
+		names[i] = child.Name
+	}
+	return strings.Join(names, " ")
+}
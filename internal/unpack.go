@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// UnpackedFile is a file read back from a dump
+type UnpackedFile struct {
+	Path    string
+	Content string
+}
+
+var (
+	tokenSuffixPattern = regexp.MustCompile(` \(\d+ tokens\)$`)
+	lineNumberPattern  = regexp.MustCompile(`^ *(\d+) \| `)
+	xmlFilePattern     = regexp.MustCompile(`^<file path="([^"]*)"[^>]*>$`)
+	xmlUnescaper       = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&amp;", "&")
+)
+
+// DetectDumpFormat guesses which output format produced dump, empty when
+// it does not look like a code2txt dump
+func DetectDumpFormat(dump string) string {
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(dump), "{"):
+		return "json"
+	case strings.Contains(dump, "\n<files>\n") || strings.HasPrefix(dump, "<repository "):
+		return "xml"
+	case strings.Contains(dump, "## File Contents\n"):
+		return "markdown"
+	case strings.Contains(dump, textContentsHeading):
+		return "text"
+	}
+	return ""
+}
+
+// textContentsHeading starts the file contents in the text format
+var textContentsHeading = "File Contents:\n" + strings.Repeat("=", 50) + "\n"
+
+// textFooterMarker follows the ruler that ends the file contents when the
+// text format has a prompt footer
+var textFooterMarker = "End of File Contents\n"
+
+// ParseDump reads the files back from a dump in format, which is detected
+// when empty. Line numbers added by --line-numbers are removed again. In
+// the text format, a content line that looks exactly like a file header
+// followed by its ruler starts a new file, and a blank line, a line of 50
+// "=" and "End of File Contents" end the files before the prompt footer.
+func ParseDump(dump, format string) ([]UnpackedFile, error) {
+	if format == "" {
+		format = DetectDumpFormat(dump)
+	}
+	dump = strings.ReplaceAll(dump, "\r\n", "\n")
+
+	var files []UnpackedFile
+	var err error
+	switch format {
+	case "text":
+		files = parseTextDump(dump)
+	case "markdown":
+		files = parseMarkdownDump(dump)
+	case "xml":
+		files = parseXMLDump(dump)
+	case "json":
+		files, err = parseJSONDump(dump)
+	case "":
+		return nil, fmt.Errorf("not a code2txt dump")
+	default:
+		return nil, fmt.Errorf("cannot unpack the %s format", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found in the %s dump", format)
+	}
+
+	for i := range files {
+		files[i].Content = stripLineNumbers(files[i].Content)
+	}
+	return files, nil
+}
+
+func parseTextDump(dump string) []UnpackedFile {
+	start := strings.Index(dump, textContentsHeading)
+	if start < 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(dump[start+len(textContentsHeading):], "\n")
+
+	// isHeader reports whether a file header and its ruler start at line i
+	isHeader := func(i int) bool {
+		if i+1 >= len(lines) || !strings.HasPrefix(lines[i], "File: ") {
+			return false
+		}
+		header := strings.TrimSuffix(lines[i], "\n")
+		return lines[i+1] == strings.Repeat("-", len(header))+"\n"
+	}
+
+	var files []UnpackedFile
+	var current *UnpackedFile
+	var content []string
+	flush := func() {
+		if current == nil {
+			return
+		}
+		text := strings.Join(content, "")
+		if text == "(empty file)\n" {
+			text = ""
+		}
+		current.Content = text
+		files = append(files, *current)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if isHeader(i) {
+			// A blank line separates a file from the next header
+			if n := len(content); n > 0 && content[n-1] == "\n" {
+				content = content[:n-1]
+			}
+			flush()
+			header := strings.TrimSuffix(lines[i], "\n")
+			current = &UnpackedFile{Path: tokenSuffixPattern.ReplaceAllString(strings.TrimPrefix(header, "File: "), "")}
+			content = nil
+			i++
+			continue
+		}
+		if current != nil {
+			content = append(content, lines[i])
+		}
+	}
+
+	if n := len(content); n > 0 && content[n-1] == "" {
+		content = content[:n-1]
+	}
+
+	// Drop the prompt footer, which follows a ruler and its marker after the
+	// last file
+	footerRuler := strings.Repeat("=", 50) + "\n"
+	for k := len(content) - 3; k >= 0; k-- {
+		if content[k] == "\n" && content[k+1] == footerRuler && content[k+2] == textFooterMarker {
+			content = content[:k]
+			break
+		}
+	}
+
+	// Drop the warning that closes an incomplete dump
+	if n := len(content); n >= 3 && strings.HasPrefix(content[n-2], "WARNING: INCOMPLETE OUTPUT") && content[n-3] == "\n" {
+		content = content[:n-3]
+	}
+	flush()
+	return files
+}
+
+func parseMarkdownDump(dump string) []UnpackedFile {
+	start := strings.Index(dump, "## File Contents\n")
+	if start < 0 {
+		return nil
+	}
+	lines := strings.Split(dump[start:], "\n")
+
+	var files []UnpackedFile
+	for i := 0; i+2 < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "### ") || lines[i+1] != "" || !strings.HasPrefix(lines[i+2], "```") {
+			continue
+		}
+		path := tokenSuffixPattern.ReplaceAllString(strings.TrimPrefix(lines[i], "### "), "")
+		opening := lines[i+2]
+		fence := opening[:len(opening)-len(strings.TrimLeft(opening, "`"))]
+
+		end := i + 3
+		for end < len(lines) && lines[end] != fence {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+
+		content := strings.Join(lines[i+3:end], "\n")
+		if end > i+3 {
+			content += "\n"
+		}
+		files = append(files, UnpackedFile{Path: path, Content: content})
+		i = end
+	}
+	return files
+}
+
+func parseXMLDump(dump string) []UnpackedFile {
+	lines := strings.SplitAfter(dump, "\n")
+
+	var files []UnpackedFile
+	for i := 0; i < len(lines); i++ {
+		match := xmlFilePattern.FindStringSubmatch(strings.TrimSuffix(lines[i], "\n"))
+		if match == nil {
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && lines[end] != "</file>\n" && lines[end] != "</file>" {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+
+		files = append(files, UnpackedFile{
+			Path:    xmlUnescaper.Replace(match[1]),
+			Content: xmlUnescaper.Replace(strings.Join(lines[i+1:end], "")),
+		})
+		i = end
+	}
+	return files
+}
+
+func parseJSONDump(dump string) ([]UnpackedFile, error) {
+	var doc jsonOutput
+	if err := json.Unmarshal([]byte(dump), &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON dump: %w", err)
+	}
+
+	files := make([]UnpackedFile, 0, len(doc.Files))
+	for _, file := range doc.Files {
+		if !file.IsDirectory {
+			files = append(files, UnpackedFile{Path: file.RelativePath, Content: file.Content})
+		}
+	}
+	return files, nil
+}
+
+// stripLineNumbers removes the prefixes added by NumberLines when every
+// line carries one, in sequence
+func stripLineNumbers(content string) string {
+	if content == "" {
+		return content
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	next := -1
+	for i, line := range lines {
+		match := lineNumberPattern.FindStringSubmatch(line)
+		if match == nil {
+			return content
+		}
+		var number int
+		fmt.Sscan(match[1], &number)
+		if next >= 0 && number != next {
+			return content
+		}
+		next = number + 1
+		lines[i] = line[len(match[0]):]
+	}
+	return strings.Join(lines, "")
+}
+
+// ValidateUnpackPath rejects paths that would be written outside the
+// target directory: absolute paths and paths with .. components
+func ValidateUnpackPath(path string) error {
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) ||
+		filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return fmt.Errorf("refusing absolute path %q", path)
+	}
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("refusing path outside the target directory %q", path)
+		}
+	}
+	if !filepath.IsLocal(filepath.FromSlash(path)) {
+		return fmt.Errorf("refusing path %q", path)
+	}
+	return nil
+}
+
+// WriteUnpacked writes files below dir. Every path is checked before
+// anything is written, and files are never written through a symlink.
+func WriteUnpacked(dir string, files []UnpackedFile) error {
+	for _, file := range files {
+		if err := ValidateUnpackPath(file.Path); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := checkNoSymlinks(dir, file.Path); err != nil {
+			return err
+		}
+		mode := fs.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := WriteFileAtomic(path, []byte(file.Content), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNoSymlinks fails when an existing component of path below dir is a
+// symlink, which could point outside dir
+func checkNoSymlinks(dir, path string) error {
+	current := dir
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through symlink %s", current)
+		}
+	}
+	return nil
+}
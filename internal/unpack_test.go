@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseDumpRoundTrip(t *testing.T) {
+	result := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "main.go", Content: "package main\n\nfunc main() {}\n", TokenCount: 8},
+			{RelativePath: "empty.txt", Content: "", TokenCount: 0},
+			{RelativePath: filepath.Join("docs", "guide.md"), Content: "Use ```go``` and <b>tags</b> & \"quotes\"\n\nFile: fake\n---\n", TokenCount: 12},
+			{RelativePath: "notes.txt", Content: "Title\n\n" + strings.Repeat("=", 50) + "\nBody\n", TokenCount: 4},
+		},
+		TotalFiles:  4,
+		TotalTokens: 20,
+	}
+	expected := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"empty.txt":     "",
+		"docs/guide.md": "Use ```go``` and <b>tags</b> & \"quotes\"\n\nFile: fake\n---\n",
+		"notes.txt":     "Title\n\n" + strings.Repeat("=", 50) + "\nBody\n",
+	}
+
+	for _, format := range OutputFormats() {
+		for _, lineNumbers := range []bool{false, true} {
+			for _, footer := range []string{"", "Find the bugs.\n\nThen fix them."} {
+				formatter := NewOutputFormatter(&OutputOptions{
+					ShowTokens: true, ShowTree: true, Format: format, LineNumbers: lineNumbers,
+					Prompt: &Prompt{Header: "Review this.", Footer: footer},
+				})
+				dump := formatter.FormatOutput(result)
+
+				if detected := DetectDumpFormat(dump); detected != format {
+					t.Errorf("Expected %s to be detected, got %q", format, detected)
+				}
+				files, err := ParseDump(dump, "")
+				if err != nil {
+					t.Fatalf("Failed to parse the %s dump: %v", format, err)
+				}
+				if len(files) != len(expected) {
+					t.Fatalf("Expected %d files from the %s dump, got %+v", len(expected), format, files)
+				}
+				for _, file := range files {
+					if content, ok := expected[file.Path]; !ok || content != file.Content {
+						t.Errorf("%s (line numbers %v, footer %q): unexpected %s: %q", format, lineNumbers, footer, file.Path, file.Content)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseIncompleteTextDump(t *testing.T) {
+	result := formatTestResult()
+	result.Incomplete = true
+	files, err := ParseDump(NewOutputFormatter(nil).FormatOutput(result), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := files[len(files)-1]; last.Path != "main.go" || last.Content != "package main\n" {
+		t.Errorf("Expected the warning to be dropped, got %q", last.Content)
+	}
+}
+
+func TestValidateUnpackPath(t *testing.T) {
+	for _, path := range []string{"main.go", "a/b/c.txt", ".github/ci.yml", "..hidden"} {
+		if err := ValidateUnpackPath(path); err != nil {
+			t.Errorf("Expected %s to be accepted: %v", path, err)
+		}
+	}
+	for _, path := range []string{"", "../evil", "a/../../evil", "/etc/passwd", `\windows\system32`, `a\..\..\evil`, "C:/evil"} {
+		if runtime.GOOS != "windows" && path == "C:/evil" {
+			continue
+		}
+		if err := ValidateUnpackPath(path); err == nil {
+			t.Errorf("Expected %s to be rejected", path)
+		}
+	}
+}
+
+func TestWriteUnpacked(t *testing.T) {
+	dir := t.TempDir()
+	files := []UnpackedFile{{Path: "a/b.txt", Content: "b\n"}, {Path: "../escape.txt", Content: "x"}}
+	if err := WriteUnpacked(dir, files); err == nil {
+		t.Fatal("Expected the unsafe path to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a", "b.txt")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written when a path is rejected")
+	}
+
+	if err := WriteUnpacked(dir, files[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a", "b.txt")); string(data) != "b\n" {
+		t.Errorf("Unexpected content %q", data)
+	}
+
+	if runtime.GOOS != "windows" {
+		script := filepath.Join(dir, "run.sh")
+		os.WriteFile(script, []byte("old\n"), 0755)
+		if err := WriteUnpacked(dir, []UnpackedFile{{Path: "run.sh", Content: "new\n"}}); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(script); err != nil {
+			t.Fatal(err)
+		} else if info.Mode().Perm() != 0755 {
+			t.Errorf("Expected the existing mode to be kept, got %v", info.Mode())
+		}
+
+		outside := t.TempDir()
+		os.Symlink(outside, filepath.Join(dir, "link"))
+		err := WriteUnpacked(dir, []UnpackedFile{{Path: "link/x.txt", Content: "x"}})
+		if err == nil || !strings.Contains(err.Error(), "symlink") {
+			t.Errorf("Expected writing through a symlink to fail, got %v", err)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	diff := UnifiedDiff("a/f", "b/f", "one\ntwo\nthree\n", "one\n2\nthree\nfour")
+	expected := "--- a/f\n+++ b/f\n@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n\\ No newline at end of file\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, diff)
+	}
+	if UnifiedDiff("a", "b", "same\n", "same\n") != "" {
+		t.Error("Expected no diff for equal texts")
+	}
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Watcher reports paths that changed below a watched root
+type Watcher interface {
+	// Events delivers the paths of created, modified and removed files
+	// and directories
+	Events() <-chan string
+	// Errors delivers problems that do not stop the watcher
+	Errors() <-chan error
+	// Name identifies the mechanism used, e.g. "inotify" or "polling"
+	Name() string
+	Close() error
+}
+
+// SkipFunc reports whether a path below the watched root should be ignored
+type SkipFunc func(path string) bool
+
+// NewWatcher watches root recursively, ignoring paths rejected by skip. It
+// uses native filesystem notifications where available and falls back to
+// polling every interval otherwise.
+func NewWatcher(root string, skip SkipFunc, interval time.Duration) (Watcher, error) {
+	if skip == nil {
+		skip = func(string) bool { return false }
+	}
+
+	if w, err := newNativeWatcher(root, skip); err == nil {
+		return w, nil
+	}
+	return NewPollingWatcher(root, skip, interval)
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// pollingWatcher detects changes by comparing periodic snapshots of the tree
+type pollingWatcher struct {
+	root     string
+	skip     SkipFunc
+	interval time.Duration
+	events   chan string
+	errors   chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewPollingWatcher watches root by walking it every interval
+func NewPollingWatcher(root string, skip SkipFunc, interval time.Duration) (Watcher, error) {
+	if skip == nil {
+		skip = func(string) bool { return false }
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	w := &pollingWatcher{
+		root:     root,
+		skip:     skip,
+		interval: interval,
+		events:   make(chan string, 64),
+		errors:   make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+
+	snapshot, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	go w.run(snapshot)
+	return w, nil
+}
+
+func (w *pollingWatcher) Events() <-chan string { return w.events }
+func (w *pollingWatcher) Errors() <-chan error  { return w.errors }
+func (w *pollingWatcher) Name() string          { return "polling" }
+
+func (w *pollingWatcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
+
+func (w *pollingWatcher) run(previous map[string]fileState) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := w.snapshot()
+		if err != nil {
+			w.sendError(err)
+			continue
+		}
+
+		for path, state := range current {
+			old, ok := previous[path]
+			if !ok || (!state.isDir && (old.size != state.size || !old.modTime.Equal(state.modTime))) {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		for path := range previous {
+			if _, ok := current[path]; !ok {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		previous = current
+	}
+}
+
+func (w *pollingWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *pollingWatcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func (w *pollingWatcher) snapshot() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Removed while walking
+				return nil
+			}
+			return err
+		}
+		if path != w.root && w.skip(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		snapshot[path] = fileState{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			isDir:   d.IsDir(),
+		}
+		return nil
+	})
+
+	return snapshot, err
+}
@@ -0,0 +1,172 @@
+//go:build linux
+
+package internal
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyWatcher watches a tree with one inotify watch per directory
+type inotifyWatcher struct {
+	file    *os.File
+	fd      int
+	root    string
+	skip    SkipFunc
+	watches map[int32]string
+	events  chan string
+	errors  chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newNativeWatcher(root string, skip SkipFunc) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		// A non-blocking descriptor is served by the runtime poller, so
+		// closing the file interrupts a pending read
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		root:    root,
+		skip:    skip,
+		watches: make(map[int32]string),
+		events:  make(chan string, 64),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+
+	// Running out of watches (fs.inotify.max_user_watches) fails here and
+	// makes NewWatcher fall back to polling
+	if err := w.addTree(root); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+func (w *inotifyWatcher) Errors() <-chan error  { return w.errors }
+func (w *inotifyWatcher) Name() string          { return "inotify" }
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
+
+// addTree adds a watch for dir and every directory below it
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.root && w.skip(path) {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+		}
+		w.watches[int32(wd)] = path
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) run() {
+	buf := make([]byte, 64*1024)
+
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.sendError(err)
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			name := strings.TrimRight(string(buf[offset+syscall.SizeofInotifyEvent:offset+syscall.SizeofInotifyEvent+nameLen]), "\x00")
+			offset += syscall.SizeofInotifyEvent + nameLen
+
+			if mask&syscall.IN_Q_OVERFLOW != 0 {
+				// Events were lost, report the root so everything is rescanned
+				if !w.send(w.root) {
+					return
+				}
+				continue
+			}
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(w.watches, wd)
+				continue
+			}
+
+			dir, ok := w.watches[wd]
+			if !ok {
+				continue
+			}
+			path := dir
+			if name != "" {
+				path = filepath.Join(dir, name)
+			}
+			if w.skip(path) {
+				continue
+			}
+
+			// Watch directories created or moved into the tree
+			if mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if err := w.addTree(path); err != nil {
+					w.sendError(err)
+				}
+			}
+
+			if !w.send(path) {
+				return
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *inotifyWatcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
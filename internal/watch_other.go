@@ -0,0 +1,9 @@
+//go:build !linux
+
+package internal
+
+import "errors"
+
+func newNativeWatcher(root string, skip SkipFunc) (Watcher, error) {
+	return nil, errors.New("native file notifications are not supported on this platform")
+}
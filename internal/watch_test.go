@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+}
+
+func TestRescanPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"main.go":        "package main\n",
+		"old.go":         "package main\n",
+		"pkg/util.go":    "package pkg\n",
+		"debug.log":      "noise\n",
+		"pkg/helper.txt": "help\n",
+	})
+
+	scanner := NewScanner(nil)
+	result, err := scanner.ScanDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+	if result.TotalFiles != 4 {
+		t.Fatalf("Expected 4 files, got %d", result.TotalFiles)
+	}
+
+	// Modify one file, delete another, add a file in a new directory and
+	// touch an excluded file
+	writeTestFiles(t, tempDir, map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"new/handler.go": "package new\n",
+		"debug.log":      "more noise\n",
+	})
+	os.Remove(filepath.Join(tempDir, "old.go"))
+
+	changed := []string{
+		filepath.Join(tempDir, "main.go"),
+		filepath.Join(tempDir, "old.go"),
+		filepath.Join(tempDir, "new"),
+		filepath.Join(tempDir, "debug.log"),
+	}
+	if err := scanner.RescanPaths(context.Background(), result, changed); err != nil {
+		t.Fatalf("RescanPaths failed: %v", err)
+	}
+
+	if result.TotalFiles != 4 {
+		t.Errorf("Expected 4 files after rescan, got %d", result.TotalFiles)
+	}
+	if content := findFile(result, "main.go").Content; content != "package main\n\nfunc main() {}\n" {
+		t.Errorf("Expected updated content, got %q", content)
+	}
+	if findFile(result, "old.go").Path != "" {
+		t.Error("Expected deleted file to be removed")
+	}
+	if findFile(result, filepath.Join("new", "handler.go")).Path == "" {
+		t.Error("Expected file in new directory to be added")
+	}
+	if findFile(result, "debug.log").Path != "" {
+		t.Error("Expected excluded file to stay excluded")
+	}
+
+	total := 0
+	for _, file := range result.Files {
+		total += file.TokenCount
+	}
+	if result.TotalTokens != total {
+		t.Errorf("Expected total tokens %d, got %d", total, result.TotalTokens)
+	}
+}
+
+func TestRescanGitignore(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		".gitignore":   "*.txt\n",
+		"main.go":      "package main\n",
+		"keep.txt":     "scratch\n",
+		"build/out.go": "package build\n",
+	})
+
+	scanner := NewScanner(nil)
+	result, err := scanner.ScanDirectory(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+	if findFile(result, "keep.txt").Path != "" {
+		t.Fatal("Expected keep.txt to be ignored")
+	}
+
+	writeTestFiles(t, tempDir, map[string]string{".gitignore": "build\n"})
+	changed := []string{filepath.Join(tempDir, ".gitignore")}
+	if err := scanner.RescanPaths(context.Background(), result, changed); err != nil {
+		t.Fatalf("RescanPaths failed: %v", err)
+	}
+
+	if findFile(result, "keep.txt").Path == "" {
+		t.Error("Expected keep.txt to be scanned once no longer ignored")
+	}
+	if findFile(result, filepath.Join("build", "out.go")).Path != "" {
+		t.Error("Expected build to be dropped by the new rules")
+	}
+	if result.TotalFiles != 2 {
+		t.Errorf("Expected 2 files after rescan, got %d", result.TotalFiles)
+	}
+}
+
+func TestExcludePaths(t *testing.T) {
+	scanner := NewScanner(&ScanOptions{ExcludePaths: map[string]bool{"out/context.txt": true}})
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{filepath.Join("out", "context.txt"), true},
+		{"context.txt", false},
+		{filepath.Join("docs", "out", "context.txt"), false},
+		{filepath.Join("out", "context.txt.bak"), false},
+	}
+
+	for _, test := range tests {
+		if result := scanner.Excludes(test.path); result != test.expected {
+			t.Errorf("Excludes(%s) = %t, expected %t", test.path, result, test.expected)
+		}
+	}
+}
+
+func TestExcludes(t *testing.T) {
+	scanner := NewScanner(&ScanOptions{ExcludePatterns: []string{"vendor", "*.log"}})
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"main.go", false},
+		{filepath.Join("vendor", "lib", "a.go"), true},
+		{filepath.Join("logs", "app.log"), true},
+		{filepath.Join("src", "app.go"), false},
+	}
+
+	for _, test := range tests {
+		if result := scanner.Excludes(test.path); result != test.expected {
+			t.Errorf("Excludes(%s) = %t, expected %t", test.path, result, test.expected)
+		}
+	}
+}
+
+func expectEvent(t *testing.T, w Watcher, path string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-w.Events():
+			if event == path {
+				return
+			}
+		case err := <-w.Errors():
+			t.Fatalf("Watcher error: %v", err)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for event on %s", path)
+		}
+	}
+}
+
+func testWatcher(t *testing.T, newWatcher func(root string, skip SkipFunc) (Watcher, error)) {
+	tempDir := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{"main.go": "package main\n"})
+
+	skip := func(path string) bool { return filepath.Ext(path) == ".log" }
+	w, err := newWatcher(tempDir, skip)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	// Skipped files never produce events, so the modification below is
+	// the first event seen
+	writeTestFiles(t, tempDir, map[string]string{"debug.log": "noise\n"})
+	writeTestFiles(t, tempDir, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	expectEvent(t, w, filepath.Join(tempDir, "main.go"))
+
+	// Files in directories created after the watcher started are seen too
+	writeTestFiles(t, tempDir, map[string]string{"sub/a.go": "package sub\n"})
+	expectEvent(t, w, filepath.Join(tempDir, "sub"))
+}
+
+func TestPollingWatcher(t *testing.T) {
+	testWatcher(t, func(root string, skip SkipFunc) (Watcher, error) {
+		return NewPollingWatcher(root, skip, 20*time.Millisecond)
+	})
+}
+
+func TestNewWatcher(t *testing.T) {
+	testWatcher(t, func(root string, skip SkipFunc) (Watcher, error) {
+		return NewWatcher(root, skip, 20*time.Millisecond)
+	})
+}
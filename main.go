@@ -7,23 +7,15 @@ import (
 	"github.com/nav9v/code2txt/cmd"
 )
 
-// version is set during build time via ldflags
-var version = "dev"
+// Build metadata is set during build time via ldflags
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
 
 func main() {
-	banner := `
-                                                                	  
-   ██████╗ ██████╗ ██████╗ ███████╗██████╗ ████████╗██╗  ██╗████████╗  
-  ██╔════╝██╔═══██╗██╔══██╗██╔════╝╚════██╗╚══██╔══╝╚██╗██╔╝╚══██╔══╝  
-  ██║     ██║   ██║██║  ██║█████╗   █████╔╝   ██║    ╚███╔╝    ██║     
-  ██║     ██║   ██║██║  ██║██╔══╝  ██╔═══╝    ██║    ██╔██╗    ██║     
-  ╚██████╗╚██████╔╝██████╔╝███████╗███████╗   ██║   ██╔╝ ██╗   ██║     
-   ╚═════╝ ╚═════╝ ╚═════╝ ╚══════╝╚══════╝   ╚═╝   ╚═╝  ╚═╝   ╚═╝     
-                                                                      `
-
-	fmt.Println(banner)
-	fmt.Printf("version %s\n", version)
-	fmt.Println()
+	cmd.SetVersionInfo(version, commit, date)
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -0,0 +1,151 @@
+package code2txt
+
+import "github.com/nav9v/code2txt/internal"
+
+// The public types mirror the internal ones, so they can evolve apart
+// without breaking embedders. These helpers convert at the boundary.
+
+func fromInternalFile(file *internal.FileInfo) *FileInfo {
+	return &FileInfo{
+		Path:         file.Path,
+		RelativePath: file.RelativePath,
+		Size:         file.Size,
+		ModTime:      file.ModTime,
+		TokenCount:   file.TokenCount,
+		Content:      file.Content,
+		IsDirectory:  file.IsDirectory,
+		Class:        file.Class,
+		Language:     file.Language,
+		StartLine:    file.StartLine,
+	}
+}
+
+func (file *FileInfo) toInternal() *internal.FileInfo {
+	return &internal.FileInfo{
+		Path:         file.Path,
+		RelativePath: file.RelativePath,
+		Size:         file.Size,
+		ModTime:      file.ModTime,
+		TokenCount:   file.TokenCount,
+		Content:      file.Content,
+		IsDirectory:  file.IsDirectory,
+		Class:        file.Class,
+		Language:     file.Language,
+		StartLine:    file.StartLine,
+	}
+}
+
+// fromInternalResult converts result, recording the converted file of
+// every internal one in files when it is not nil
+func fromInternalResult(result *internal.ScanResult, files map[*internal.FileInfo]*FileInfo) *ScanResult {
+	if result == nil {
+		return nil
+	}
+	converted := &ScanResult{
+		RootPath:    result.RootPath,
+		Files:       make([]*FileInfo, len(result.Files)),
+		TotalTokens: result.TotalTokens,
+		TotalFiles:  result.TotalFiles,
+		Incomplete:  result.Incomplete,
+		namesOnly:   result.NamesOnly,
+	}
+	for i, file := range result.Files {
+		converted.Files[i] = fromInternalFile(file)
+		if files != nil {
+			files[file] = converted.Files[i]
+		}
+	}
+	return converted
+}
+
+func (result *ScanResult) toInternal() *internal.ScanResult {
+	converted := &internal.ScanResult{
+		RootPath:    result.RootPath,
+		Files:       make([]*internal.FileInfo, len(result.Files)),
+		TotalTokens: result.TotalTokens,
+		TotalFiles:  result.TotalFiles,
+		Incomplete:  result.Incomplete,
+		NamesOnly:   result.namesOnly,
+	}
+	for i, file := range result.Files {
+		converted.Files[i] = file.toInternal()
+	}
+	return converted
+}
+
+func fromInternalTree(node *internal.TreeNode, parent *TreeNode) *TreeNode {
+	converted := &TreeNode{
+		Name:        node.Name,
+		Path:        node.Path,
+		IsDirectory: node.IsDirectory,
+		TokenCount:  node.TokenCount,
+		Size:        node.Size,
+		TotalTokens: node.TotalTokens,
+		TotalSize:   node.TotalSize,
+		Files:       node.Files,
+		Class:       node.Class,
+		Children:    make([]*TreeNode, len(node.Children)),
+		Parent:      parent,
+	}
+	for i, child := range node.Children {
+		converted.Children[i] = fromInternalTree(child, converted)
+	}
+	return converted
+}
+
+// toInternal converts the tree below node, recording the public node of
+// every converted one in nodes when it is not nil
+func (node *TreeNode) toInternal(parent *internal.TreeNode, nodes map[*internal.TreeNode]*TreeNode) *internal.TreeNode {
+	converted := &internal.TreeNode{
+		Name:        node.Name,
+		Path:        node.Path,
+		IsDirectory: node.IsDirectory,
+		TokenCount:  node.TokenCount,
+		Size:        node.Size,
+		TotalTokens: node.TotalTokens,
+		TotalSize:   node.TotalSize,
+		Files:       node.Files,
+		Class:       node.Class,
+		Children:    make([]*internal.TreeNode, len(node.Children)),
+		Parent:      parent,
+	}
+	if nodes != nil {
+		nodes[converted] = node
+	}
+	for i, child := range node.Children {
+		converted.Children[i] = child.toInternal(converted, nodes)
+	}
+	return converted
+}
+
+// reorderTree gives the public nodes the order of their sorted internal
+// copies
+func reorderTree(sorted *internal.TreeNode, nodes map[*internal.TreeNode]*TreeNode) {
+	node := nodes[sorted]
+	for i, child := range sorted.Children {
+		node.Children[i] = nodes[child]
+		reorderTree(child, nodes)
+	}
+}
+
+// fromInternalTemplateData converts data for templates set with
+// WithTemplate, so they see the public types
+func fromInternalTemplateData(data *internal.TemplateData) any {
+	files := make(map[*internal.FileInfo]*FileInfo)
+	converted := &TemplateData{
+		Result:      fromInternalResult(data.Result, files),
+		Name:        data.Name,
+		Tree:        data.Tree,
+		Files:       make([]*FileInfo, len(data.Files)),
+		Warning:     data.Warning,
+		Header:      data.Header,
+		Footer:      data.Footer,
+		TotalTokens: data.TotalTokens,
+		ShowTokens:  data.ShowTokens,
+		ShowTree:    data.ShowTree,
+	}
+	for i, file := range data.Files {
+		converted.Files[i] = files[file]
+	}
+	return converted
+}
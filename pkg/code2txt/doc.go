@@ -0,0 +1,23 @@
+// Package code2txt scans source trees and renders them as AI-friendly text.
+//
+// It is the public, importable API of the code2txt command line tool. A
+// typical embedder scans a directory, optionally builds the directory tree,
+// and writes the result through one of the formatters:
+//
+//	result, err := code2txt.Scan(ctx, "./my-project",
+//		code2txt.WithInclude("*.go"),
+//		code2txt.WithMaxTokens(5000),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	err = code2txt.NewTextFormatter(code2txt.WithTokens(true)).Format(os.Stdout, result)
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are not removed or changed incompatibly, and new behaviour is
+// added through new options rather than new parameters. The exact text of
+// the rendered output and the token estimates are not part of the
+// compatibility promise and may improve between minor releases.
+package code2txt
@@ -0,0 +1,131 @@
+package code2txt_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	"github.com/nav9v/code2txt/pkg/code2txt"
+)
+
+func ExampleScan() {
+	dir, _ := os.MkdirTemp("", "code2txt-example")
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("noise\n"), 0644)
+
+	result, err := code2txt.Scan(context.Background(), dir, code2txt.WithInclude("*.go"))
+	if err != nil {
+		fmt.Println("scan failed:", err)
+		return
+	}
+
+	for _, file := range result.Files {
+		if !file.IsDirectory {
+			fmt.Println(file.RelativePath)
+		}
+	}
+	fmt.Println("files:", result.TotalFiles)
+	// Output:
+	// main.go
+	// files: 1
+}
+
+func ExampleScanFS() {
+	fsys := fstest.MapFS{
+		"main.go":        {Data: []byte("package main\n")},
+		"docs/readme.md": {Data: []byte("# Project\n")},
+	}
+
+	result, err := code2txt.ScanFS(context.Background(), fsys, "project")
+	if err != nil {
+		fmt.Println("scan failed:", err)
+		return
+	}
+
+	fmt.Print(code2txt.RenderTree(code2txt.BuildTree(result), false))
+	// Output:
+	// project
+	// ├── docs
+	// │   └── readme.md [docs]
+	// └── main.go
+}
+
+func ExampleBuildTree() {
+	result := &code2txt.ScanResult{
+		RootPath: "project",
+		Files: []*code2txt.FileInfo{
+			{RelativePath: "main.go", TokenCount: 12},
+			{RelativePath: "internal/app.go", TokenCount: 30},
+		},
+	}
+
+	fmt.Print(code2txt.RenderTree(code2txt.BuildTree(result), true))
+	// Output:
+	// project (2 files, 42 tokens)
+	// ├── internal (1 file, 30 tokens)
+	// │   └── app.go (30 tokens)
+	// └── main.go (12 tokens)
+}
+
+func ExampleRenderTreeOptions() {
+	result := &code2txt.ScanResult{
+		RootPath: "project",
+		Files: []*code2txt.FileInfo{
+			{RelativePath: "main.go", Size: 400},
+			{RelativePath: "internal/app.go", Size: 1200},
+		},
+	}
+
+	fmt.Print(code2txt.RenderTreeOptions(code2txt.BuildTree(result), code2txt.TreeOptions{ShowSize: true, MaxDepth: 1}))
+	// Output:
+	// project (2 files, 1.6 KB)
+	// ├── internal (1 file, 1.2 KB)
+	// └── main.go (400 B)
+}
+
+func ExampleNewTextFormatter() {
+	result := &code2txt.ScanResult{
+		RootPath: "project",
+		Files: []*code2txt.FileInfo{
+			{RelativePath: "hello.txt", Content: "Hello, World!\n", TokenCount: 5},
+		},
+		TotalFiles:  1,
+		TotalTokens: 5,
+	}
+
+	formatter := code2txt.NewTextFormatter(code2txt.WithTree(false))
+	formatter.Format(os.Stdout, result)
+	// Output:
+	// File Contents:
+	// ==================================================
+	//
+	// File: hello.txt
+	// ---------------
+	// Hello, World!
+}
+
+func ExampleWithTemplate() {
+	fsys := fstest.MapFS{
+		"main.go": {Data: []byte("package main\n")},
+		"util.go": {Data: []byte("package main\n\nfunc util() {}\n")},
+	}
+	result, err := code2txt.ScanFS(context.Background(), fsys, "project")
+	if err != nil {
+		fmt.Println("scan failed:", err)
+		return
+	}
+
+	tmpl, err := code2txt.ParseTemplate("list", `{{range .Files}}{{.RelativePath}}: {{tokens .Content}} tokens
+{{end}}`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	code2txt.NewTextFormatter(code2txt.WithTemplate(tmpl)).Format(os.Stdout, result)
+	// Output:
+	// main.go: 3 tokens
+	// util.go: 7 tokens
+}
@@ -0,0 +1,163 @@
+package code2txt
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/nav9v/code2txt/internal"
+)
+
+// Formatter renders a scan result
+type Formatter interface {
+	Format(w io.Writer, result *ScanResult) error
+}
+
+// TemplateData is the value output templates are executed with
+type TemplateData struct {
+	// Result is the scan being rendered
+	Result *ScanResult
+	// Name is the base name of the scanned root
+	Name string
+	// Tree is the rendered directory tree, empty unless the tree is shown
+	Tree string
+	// Files are the files of Result without directories, sorted by path
+	Files []*FileInfo
+	// Warning explains why the output is incomplete, empty otherwise
+	Warning string
+	// Header and Footer are the prompt set with WithPrompt
+	Header string
+	Footer string
+	// TotalTokens counts the files and the prompt
+	TotalTokens int
+	ShowTokens  bool
+	ShowTree    bool
+}
+
+// ParseTemplate parses an output template for WithTemplate. Besides the
+// standard text/template functions it can use the helpers of the built-in
+// templates, such as fence, language, tokens and humanize.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	funcs := internal.TemplateFuncs()
+	funcs["language"] = func(file *FileInfo) string {
+		return internal.FenceLanguage(file.Language, file.RelativePath)
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// BuiltinTemplate returns the source of the template behind the text,
+// markdown or xml format, as a starting point for a custom one
+func BuiltinTemplate(name string) (string, error) {
+	return internal.BuiltinTemplate(name)
+}
+
+// FormatOption configures the built-in formatters
+type FormatOption func(*formatOptions)
+
+// formatOptions collects the settings of the FormatOption values
+type formatOptions struct {
+	showTokens  bool
+	showTree    bool
+	format      string
+	lineNumbers bool
+	template    *template.Template
+	prompt      *internal.Prompt
+}
+
+// WithTokens adds token counts to the tree and file headers
+func WithTokens(show bool) FormatOption {
+	return func(o *formatOptions) {
+		o.showTokens = show
+	}
+}
+
+// WithTree controls whether the directory tree precedes the file contents
+func WithTree(show bool) FormatOption {
+	return func(o *formatOptions) {
+		o.showTree = show
+	}
+}
+
+// WithFormat selects the layout of NewTextFormatter: "text" (default),
+// "markdown", "xml" or "json"
+func WithFormat(name string) FormatOption {
+	return func(o *formatOptions) {
+		o.format = name
+	}
+}
+
+// WithLineNumbers prefixes every line of file content with its line
+// number. Token counts in the output are computed on the numbered content.
+func WithLineNumbers(show bool) FormatOption {
+	return func(o *formatOptions) {
+		o.lineNumbers = show
+	}
+}
+
+// WithTemplate renders NewTextFormatter output through tmpl, executed with
+// a *TemplateData, instead of the selected format
+func WithTemplate(tmpl *template.Template) FormatOption {
+	return func(o *formatOptions) {
+		o.template = tmpl
+	}
+}
+
+// WithPrompt writes header before and footer after the output. Their
+// tokens are included in the totals.
+func WithPrompt(header, footer string) FormatOption {
+	return func(o *formatOptions) {
+		o.prompt = &internal.Prompt{Header: header, Footer: footer}
+	}
+}
+
+func newOutputFormatter(opts []FormatOption) *internal.OutputFormatter {
+	options := &formatOptions{showTree: true}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return internal.NewOutputFormatter(&internal.OutputOptions{
+		ShowTokens:   options.showTokens,
+		ShowTree:     options.showTree,
+		Format:       options.format,
+		LineNumbers:  options.lineNumbers,
+		Template:     options.template,
+		TemplateData: fromInternalTemplateData,
+		Prompt:       options.prompt,
+	})
+}
+
+type formatterFunc func(w io.Writer, result *internal.ScanResult) error
+
+func (f formatterFunc) Format(w io.Writer, result *ScanResult) error {
+	return f(w, result.toInternal())
+}
+
+// NewTextFormatter returns the formatter used by the code2txt command: the
+// directory tree followed by the contents of every file
+func NewTextFormatter(opts ...FormatOption) Formatter {
+	formatter := newOutputFormatter(opts)
+	return formatterFunc(formatter.WriteOutput)
+}
+
+// NewFileListFormatter returns a formatter listing files with their token
+// counts
+func NewFileListFormatter(opts ...FormatOption) Formatter {
+	formatter := newOutputFormatter(opts)
+	return formatterFunc(func(w io.Writer, result *internal.ScanResult) error {
+		_, err := io.WriteString(w, formatter.FormatFileList(result))
+		return err
+	})
+}
+
+// NewSummaryFormatter returns a formatter printing file and token totals
+func NewSummaryFormatter(opts ...FormatOption) Formatter {
+	formatter := newOutputFormatter(opts)
+	return formatterFunc(func(w io.Writer, result *internal.ScanResult) error {
+		_, err := io.WriteString(w, formatter.FormatSummary(result))
+		return err
+	})
+}
@@ -0,0 +1,242 @@
+package code2txt
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"github.com/nav9v/code2txt/internal"
+)
+
+// FileInfo describes a scanned file or directory
+type FileInfo struct {
+	// Path is the file on disk, empty for files scanned from an fs.FS
+	Path string `json:"-"`
+	// RelativePath is the path below the scanned root
+	RelativePath string    `json:"path"`
+	Size         int64     `json:"size"`
+	ModTime      time.Time `json:"-"`
+	TokenCount   int       `json:"tokens"`
+	Content      string    `json:"content,omitempty"`
+	IsDirectory  bool      `json:"isDirectory,omitempty"`
+	// Class tells source files apart from tests, generated code and
+	// others, such as "source" or "test"
+	Class string `json:"class,omitempty"`
+	// Language is the detected language name, empty when unknown
+	Language string `json:"language,omitempty"`
+	// StartLine is the number of the first line of Content when it is one
+	// part of a longer file; zero means the file starts at 1
+	StartLine int `json:"startLine,omitempty"`
+}
+
+// ScanResult holds the files found by a scan and their totals
+type ScanResult struct {
+	RootPath    string      `json:"root"`
+	Files       []*FileInfo `json:"files"`
+	TotalTokens int         `json:"totalTokens"`
+	TotalFiles  int         `json:"totalFiles"`
+	// Incomplete is set when the scan was cancelled before it finished
+	Incomplete bool `json:"incomplete,omitempty"`
+
+	// namesOnly is set by WithoutContent, so there are no token counts to
+	// report
+	namesOnly bool
+}
+
+// TreeNode is a node of the directory tree built from a ScanResult
+type TreeNode struct {
+	Name        string `json:"name"`
+	Path        string `json:"-"`
+	IsDirectory bool   `json:"isDirectory,omitempty"`
+	TokenCount  int    `json:"tokens"`
+	Size        int64  `json:"-"`
+	// TotalTokens, TotalSize and Files add up everything below a
+	// directory
+	TotalTokens int         `json:"totalTokens,omitempty"`
+	TotalSize   int64       `json:"totalSize,omitempty"`
+	Files       int         `json:"files,omitempty"`
+	Class       string      `json:"class,omitempty"`
+	Children    []*TreeNode `json:"children,omitempty"`
+	Parent      *TreeNode   `json:"-"`
+}
+
+// ScanProgress is a snapshot of a running scan
+type ScanProgress struct {
+	FilesScanned int
+	// TotalFiles is the number of files the scan will visit, counted
+	// before it starts
+	TotalFiles    int
+	BytesRead     int64
+	TokensCounted int
+	// CurrentPath is the file scanned last, relative to the root
+	CurrentPath string
+	// Done is set on the final call, once the scan is over
+	Done bool
+}
+
+// TreeOptions controls the annotations, depth and filtering of
+// RenderTreeOptions
+type TreeOptions struct {
+	// ShowTokens annotates files with their token counts, and directories
+	// with their file count and total tokens
+	ShowTokens bool
+	// ShowSize annotates files with their size, and directories with their
+	// file count and total size
+	ShowSize bool
+	// MaxDepth hides the nodes more than this many levels below the root,
+	// 0 shows the whole tree
+	MaxDepth int
+	// DirsOnly leaves files out
+	DirsOnly bool
+	// Collapse folds the small files of a directory holding more than this
+	// many files into a single line with their totals, 0 never folds
+	Collapse int
+}
+
+// Option configures a Scanner
+type Option func(*internal.ScanOptions)
+
+// WithInclude only keeps files whose name matches one of the patterns
+func WithInclude(patterns ...string) Option {
+	return func(o *internal.ScanOptions) {
+		o.IncludePatterns = append(o.IncludePatterns, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching one of the patterns.
+// Setting any exclude pattern replaces the default exclude list.
+func WithExclude(patterns ...string) Option {
+	return func(o *internal.ScanOptions) {
+		o.ExcludePatterns = append(o.ExcludePatterns, patterns...)
+	}
+}
+
+// WithLanguages only keeps files detected as one of the languages, given
+// by name or alias such as "go" or "proto"
+func WithLanguages(names ...string) Option {
+	return func(o *internal.ScanOptions) {
+		o.Languages = append(o.Languages, names...)
+	}
+}
+
+// WithMaxTokens skips files estimated at more than n tokens (0 = no limit)
+func WithMaxTokens(n int) Option {
+	return func(o *internal.ScanOptions) {
+		o.MaxTokens = n
+	}
+}
+
+// WithoutContent lists files without reading them, for trees and file
+// lists that need no contents or token counts
+func WithoutContent() Option {
+	return func(o *internal.ScanOptions) {
+		o.SkipContent = true
+	}
+}
+
+// WithProgress calls fn as files are scanned and once more when the scan
+// is done. fn is called synchronously and should return quickly.
+func WithProgress(fn func(ScanProgress)) Option {
+	return func(o *internal.ScanOptions) {
+		o.Progress = func(p internal.ScanProgress) {
+			fn(ScanProgress{
+				FilesScanned:  p.FilesScanned,
+				TotalFiles:    p.TotalFiles,
+				BytesRead:     p.BytesRead,
+				TokensCounted: p.TokensCounted,
+				CurrentPath:   p.CurrentPath,
+				Done:          p.Done,
+			})
+		}
+	}
+}
+
+// WithCacheDir reuses file contents and token counts cached in dir by
+// previous scans, skipping files whose size and modification time did not
+// change. The directory may be shared with the code2txt command and with
+// concurrent processes.
+func WithCacheDir(dir string) Option {
+	return func(o *internal.ScanOptions) {
+		o.Cache = internal.NewCache(dir)
+	}
+}
+
+// Scanner walks directories and collects file contents and token counts.
+// A Scanner is not safe for concurrent use.
+type Scanner struct {
+	scanner *internal.Scanner
+}
+
+// NewScanner creates a Scanner configured with opts
+func NewScanner(opts ...Option) *Scanner {
+	options := &internal.ScanOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Scanner{
+		scanner: internal.NewScanner(options),
+	}
+}
+
+// Scan walks root and returns the files it contains. It stops and returns
+// ctx.Err() when ctx is cancelled.
+func (s *Scanner) Scan(ctx context.Context, root string) (*ScanResult, error) {
+	result, err := s.scanner.ScanDirectoryContext(ctx, root)
+	return fromInternalResult(result, nil), err
+}
+
+// ScanFS walks fsys, such as an embed.FS, an fstest.MapFS or an overlay,
+// and returns the files it contains. name is reported as the root path.
+// The cache is not used, since fsys may not have reliable modification
+// times.
+func (s *Scanner) ScanFS(ctx context.Context, fsys fs.FS, name string) (*ScanResult, error) {
+	result, err := s.scanner.ScanFS(ctx, fsys, name)
+	return fromInternalResult(result, nil), err
+}
+
+// Scan is a shorthand for NewScanner(opts...).Scan(ctx, root)
+func Scan(ctx context.Context, root string, opts ...Option) (*ScanResult, error) {
+	return NewScanner(opts...).Scan(ctx, root)
+}
+
+// ScanFS is a shorthand for NewScanner(opts...).ScanFS(ctx, fsys, name)
+func ScanFS(ctx context.Context, fsys fs.FS, name string, opts ...Option) (*ScanResult, error) {
+	return NewScanner(opts...).ScanFS(ctx, fsys, name)
+}
+
+// BuildTree creates the directory tree for a scan result
+func BuildTree(result *ScanResult) *TreeNode {
+	return fromInternalTree(internal.BuildTree(result.toInternal()), nil)
+}
+
+// RenderTree renders a tree with box-drawing characters
+func RenderTree(root *TreeNode, showTokens bool) string {
+	return internal.RenderTree(root.toInternal(nil, nil), showTokens)
+}
+
+// RenderTreeOptions renders a tree like RenderTree, with sizes, directory
+// totals, a depth limit or only directories
+func RenderTreeOptions(root *TreeNode, options TreeOptions) string {
+	return internal.RenderTreeOptions(root.toInternal(nil, nil), internal.TreeOptions{
+		ShowTokens: options.ShowTokens,
+		ShowSize:   options.ShowSize,
+		MaxDepth:   options.MaxDepth,
+		DirsOnly:   options.DirsOnly,
+		Collapse:   options.Collapse,
+	})
+}
+
+// SortTree reorders a tree by "name", "size" or "tokens", largest first
+// for the latter two
+func SortTree(root *TreeNode, by string) {
+	nodes := make(map[*internal.TreeNode]*TreeNode)
+	sorted := root.toInternal(nil, nodes)
+	internal.SortTree(sorted, by)
+	reorderTree(sorted, nodes)
+}
+
+// CountTokens estimates the number of LLM tokens in text
+func CountTokens(text string) int {
+	return internal.CountTokens(text)
+}
@@ -0,0 +1,111 @@
+package code2txt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScanCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Scan(ctx, tempDir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestScanOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"small.go": "package small\n",
+		"large.go": "package large\n\nvar words = \"one two three four five six seven eight nine ten\"\n",
+		"notes.md": "# Notes\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	progressCalls := 0
+	var last ScanProgress
+	result, err := Scan(context.Background(), tempDir,
+		WithInclude("*.go"),
+		WithMaxTokens(5),
+		WithProgress(func(progress ScanProgress) {
+			progressCalls++
+			last = progress
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+
+	if result.TotalFiles != 1 {
+		t.Errorf("Expected only small.go to be kept, got %d files", result.TotalFiles)
+	}
+	if progressCalls == 0 {
+		t.Error("Expected progress callback to be called")
+	}
+	if !last.Done || last.FilesScanned == 0 {
+		t.Errorf("Expected a final progress update with the scanned files, got %+v", last)
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	result := &ScanResult{
+		RootPath: "project",
+		Files: []*FileInfo{
+			{RelativePath: "a.go", TokenCount: 1},
+			{RelativePath: "b.go", TokenCount: 9},
+		},
+	}
+	root := BuildTree(result)
+	SortTree(root, "tokens")
+
+	if len(root.Children) != 2 || root.Children[0].Name != "b.go" || root.Children[1].Name != "a.go" {
+		t.Fatalf("Expected b.go to be sorted first, got %+v", root.Children)
+	}
+	if root.Children[0].Parent != root {
+		t.Error("Expected the sorted nodes to keep their parent")
+	}
+}
+
+func TestFormatPublicTypes(t *testing.T) {
+	fsys := fstest.MapFS{"main.go": {Data: []byte("package main\n")}}
+	result, err := ScanFS(context.Background(), fsys, "project", WithoutContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var summary strings.Builder
+	if err := NewSummaryFormatter().Format(&summary, result); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(summary.String(), "Total Tokens") {
+		t.Errorf("Expected no token total for a scan without content, got:\n%s", summary.String())
+	}
+
+	tmpl, err := ParseTemplate("list", `{{range .Files}}{{.RelativePath}} {{language .}} {{$.Result.RootPath}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output strings.Builder
+	if err := NewTextFormatter(WithTemplate(tmpl)).Format(&output, result); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "main.go go project" {
+		t.Errorf("Unexpected template output %q", output.String())
+	}
+}